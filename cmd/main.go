@@ -2,8 +2,10 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -13,10 +15,60 @@ import (
 	"sekai-overlay-go/internal/ui"
 )
 
+// クレジットのコマンドライン引数（指定された項目は入力を省略する）
+var (
+	wordsFlag   = flag.String("words", "", "作詞者")
+	musicFlag   = flag.String("music", "", "作曲者")
+	arrangeFlag = flag.String("arrange", "", "編曲者")
+	vocalFlag   = flag.String("vocal", "", "ボーカル (- でInst. ver.)")
+)
+
+// タイミングのコマンドライン引数（秒、負の値の場合は譜面と音源から自動で決定する）
+var (
+	leadInFlag         = flag.Float64("lead-in", modules.AutoTiming, "UI表示からBGM開始までの秒数 (負の値で自動)")
+	endScreenDelayFlag = flag.Float64("end-delay", modules.AutoTiming, "最後のノーツからエンド画面までの秒数 (負の値で自動)")
+	fadeDelayFlag      = flag.Float64("fade-delay", modules.AutoTiming, "エンド画面からフェードアウト開始までの秒数 (負の値で自動)")
+	fadeDurationFlag   = flag.Float64("fade-duration", modules.AutoTiming, "フェードアウトの秒数 (負の値で自動)")
+	fadeHoldFlag       = flag.Float64("fade-hold", modules.AutoTiming, "フェードアウト後に画面を残す秒数 (負の値で自動)")
+)
+
+// ダウンロードする素材の代わりに使うローカルのファイルのコマンドライン引数
+var (
+	jacketFlag = flag.String("jacket", "", "サーバーのカバー画像の代わりに使うジャケット画像のパス")
+	audioFlag  = flag.String("audio", "", "サーバーのBGMの代わりに使う音源(MP3/WAV/Ogg)のパス")
+)
+
+// 背景生成のコマンドライン引数
+var (
+	resampleFlag      = flag.String("resample", "", "背景のジャケットの補間方法 (bilinear/bicubic/lanczos、デフォルト: bilinear)")
+	blurRadiusFlag    = flag.Float64("blur-radius", modules.DefaultBlurRadius, "background_blur.pngのぼかしの強さ (px)")
+	dimBrightnessFlag = flag.Float64("dim-brightness", modules.DefaultDimBrightness, "background_dim.pngの明るさ (0〜1)")
+)
+
+// setFlags は明示的に指定されたコマンドライン引数の一覧
+var setFlags = map[string]bool{}
+
 func main() {
+	flag.Parse()
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
 	console := ui.NewConsole()
 	console.PrintBanner()
 
+	// AviUtl2のスクリプトディレクトリを決定する（設定ファイル・ポータブル版・標準のパスの順）
+	scriptDir := modules.ResolveScriptDir()
+	console.PrintInfo(fmt.Sprintf("スクリプトディレクトリ: %s (%s)", scriptDir.Path, scriptDir.Label))
+
+	// コマンドが指定された場合はそれだけを実行して終了する
+	if command := flag.Arg(0); command != "" {
+		if !runCommand(console, command) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 起動時に最新リリースとobj2の状態を確認して通知する（自動置換は行わない）
 	console.PrintInfo("起動チェック: 最新リリースと @SekaiObjects.obj2 の状態を確認します...")
 	if err := modules.CheckAndNotifyUpdates(console); err != nil {
@@ -34,10 +86,14 @@ func main() {
 		case "2":
 			runSetup(console)
 		case "3":
+			runUninstall(console, true)
+		case "4":
+			runRollback(console)
+		case "5":
 			console.PrintInfo("ご利用ありがとうございました。")
 			os.Exit(0)
 		default:
-			console.PrintError("無効な選択です。1-3の数字を入力してください。")
+			console.PrintError("無効な選択です。1-5の数字を入力してください。")
 		}
 
 		console.PrintInfo("\n続けるにはEnterキーを押してください...")
@@ -52,8 +108,10 @@ func showMainMenu(console *ui.Console) {
 	console.PrintHeader("メインメニュー")
 	fmt.Println("1. 譜面データ生成")
 	fmt.Println("2. セットアップ")
-	fmt.Println("3. 終了")
-	fmt.Print("\n選択してください (1-3): ")
+	fmt.Println("3. アンインストール")
+	fmt.Println("4. ロールバック（前回のセットアップ・アンインストールを元に戻す）")
+	fmt.Println("5. 終了")
+	fmt.Print("\n選択してください (1-5): ")
 }
 
 func getUserChoice(console *ui.Console) string {
@@ -85,6 +143,9 @@ func runGeneration(console *ui.Console) {
 	console.PrintInfo("譜面制作者を入力してください (空白でlevel.jsonの値を使用): ")
 	author := getUserChoice(console)
 
+	// クレジットの取得（level.jsonから取得できた値をデフォルトにする）
+	credits := fetchCredits(console, levelID)
+
 	// チーム総合力の入力
 	console.PrintInfo("チーム総合力を入力してください (デフォルト: 250000): ")
 	powerInput := getUserChoice(console)
@@ -97,14 +158,21 @@ func runGeneration(console *ui.Console) {
 		}
 	}
 
-	// 背景バージョンの選択
-	console.PrintInfo("背景バージョンを選択してください (3または1、デフォルト: 3): ")
-	versionInput := getUserChoice(console)
-	bgVersion := "3"
-	if versionInput == "1" {
-		bgVersion = "1"
-	} else if versionInput != "" && versionInput != "3" {
-		console.PrintError("無効なバージョンです。デフォルト値(3)を使用します。")
+	// 背景バージョンの選択（譜面の背景が使えない場合にも使う）
+	bgVersion := modules.DefaultBackgroundLayout
+	layouts, err := modules.ListBackgroundLayouts()
+	if err != nil {
+		console.PrintError(fmt.Sprintf("背景レイアウトの一覧の取得に失敗しました: %v", err))
+	}
+	if len(layouts) > 1 {
+		console.PrintInfo(fmt.Sprintf("背景バージョンを選択してください (%s、デフォルト: %s): ", strings.Join(layouts, "/"), modules.DefaultBackgroundLayout))
+		if versionInput := getUserChoice(console); versionInput != "" {
+			if name, err := modules.ResolveBackgroundLayout(versionInput); err == nil {
+				bgVersion = name
+			} else {
+				console.PrintError(fmt.Sprintf("無効なバージョンです。デフォルト値(%s)を使用します。", modules.DefaultBackgroundLayout))
+			}
+		}
 	}
 
 	// 難易度の入力
@@ -114,28 +182,137 @@ func runGeneration(console *ui.Console) {
 		difficulty = "master"
 	}
 
+	// 詳細設定はまとめて確認し、変更しない場合はデフォルト値とコマンドライン引数を使う
+	opts := defaultGenerationOptions(console, credits)
+	console.PrintInfo("詳細設定（クレジット・背景・解像度・切り抜き・追加の出力など）を変更しますか？ (y/N): ")
+	if advancedInput := strings.ToLower(getUserChoice(console)); advancedInput == "y" || advancedInput == "yes" {
+		readGenerationOptions(console, credits, &opts)
+	}
+
+	// 補間方法の確認
+	resample := *resampleFlag
+	if _, err := modules.ParseResampleFilter(resample); err != nil {
+		console.PrintError(fmt.Sprintf("%v。デフォルト値(%s)を使用します。", err, modules.DefaultResampleFilter))
+		resample = ""
+	}
+
+	// 派生画像の設定の確認
+	blurRadius := *blurRadiusFlag
+	if blurRadius <= 0 {
+		console.PrintError(fmt.Sprintf("ぼかしの強さは0より大きい値を指定してください。デフォルト値(%.0f)を使用します。", modules.DefaultBlurRadius))
+		blurRadius = modules.DefaultBlurRadius
+	}
+	dimBrightness := *dimBrightnessFlag
+	if dimBrightness <= 0 || dimBrightness > 1 {
+		console.PrintError(fmt.Sprintf("明るさは0〜1で指定してください。デフォルト値(%.1f)を使用します。", modules.DefaultDimBrightness))
+		dimBrightness = modules.DefaultDimBrightness
+	}
+
 	// 設定の作成
 	cfg := config.Config{
-		FullLevelID: levelID,
-		BgVersion:   bgVersion,
-		TeamPower:   teamPower,
-		AppVersion:  config.AppVersion,
+		FullLevelID:        levelID,
+		BgVersion:          bgVersion,
+		BgSource:           string(opts.bgSource),
+		BgImage:            opts.bgImage,
+		JacketOverride:     opts.jacketOverride,
+		AudioOverride:      opts.audioOverride,
+		TeamPower:          teamPower,
+		Template:           opts.templateName,
+		FPS:                opts.fps,
+		Resolution:         opts.resolution,
+		Layout:             opts.layout,
+		ClipStart:          opts.clipStart,
+		ClipEnd:            opts.clipEnd,
+		NoEndScreen:        opts.noEndScreen,
+		ExportAup2:         opts.exportAup2,
+		StartScreen:        opts.startScreen,
+		JacketTheme:        opts.jacketTheme,
+		FontPath:           opts.fontPath,
+		RenderOverlay:      opts.renderOverlay,
+		BackgroundVariants: opts.backgroundVariants,
+		BlurRadius:         blurRadius,
+		DimBrightness:      dimBrightness,
+		ExportTimelines:    opts.exportTimelines,
+		ChartPreview:       opts.chartPreview,
+		Resample:           resample,
+		NoteSpeed:          opts.noteSpeed,
+		LeadIn:             *leadInFlag,
+		EndScreenDelay:     *endScreenDelayFlag,
+		FadeDelay:          *fadeDelayFlag,
+		FadeDuration:       *fadeDurationFlag,
+		FadeHold:           *fadeHoldFlag,
+		AppVersion:         config.AppVersion,
 		ExtraData: map[string]interface{}{
 			"difficulty": difficulty,
 			"title":      title,
 			"author":     author,
+			"words":      opts.words,
+			"music":      opts.music,
+			"arrange":    opts.arrange,
+			"vocal":      opts.vocal,
 		},
 	}
 
 	// 生成前に設定サマリを表示
+	endScreenLabel := "あり"
+	if cfg.NoEndScreen {
+		endScreenLabel = "なし"
+	}
+	startScreenLabel := "AviUtlのテキスト"
+	if cfg.StartScreen {
+		startScreenLabel = "画像で生成"
+		if cfg.JacketTheme {
+			startScreenLabel += " (ジャケットの配色)"
+		}
+	}
+	projectLabel := "出力しない"
+	if cfg.ExportAup2 {
+		projectLabel = "出力する"
+	}
+	variantsLabel := "出力しない"
+	if cfg.BackgroundVariants {
+		variantsLabel = fmt.Sprintf("出力する (ぼかし %.0fpx、明るさ %.0f%%)", cfg.BlurRadius, cfg.DimBrightness*100)
+	}
+	overlayLabel := "出力しない"
+	if cfg.RenderOverlay {
+		overlayLabel = "出力する"
+	}
+	previewLabel := "出力しない"
+	if cfg.ChartPreview {
+		previewLabel = fmt.Sprintf("出力する (ノーツスピード %.1f)", cfg.NoteSpeed)
+	}
+	timelineLabel := "出力しない"
+	if cfg.ExportTimelines {
+		timelineLabel = "出力する"
+	}
 	console.PrintInfo("生成設定:")
 	summary := map[string]string{
-		"譜面ID":    cfg.FullLevelID,
-		"背景バージョン": cfg.BgVersion,
-		"チーム総合力":  fmt.Sprintf("%.0f", cfg.TeamPower),
-		"難易度":     fmt.Sprintf("%v", cfg.ExtraData["difficulty"]),
-		"タイトル":    fmt.Sprintf("%v", cfg.ExtraData["title"]),
-		"作者":      fmt.Sprintf("%v", cfg.ExtraData["author"]),
+		"譜面ID":     cfg.FullLevelID,
+		"背景":       formatBackground(cfg),
+		"ジャケット":    formatOverride(cfg.JacketOverride),
+		"音源":       formatOverride(cfg.AudioOverride),
+		"チーム総合力":   fmt.Sprintf("%.0f", cfg.TeamPower),
+		"テンプレート":   cfg.Template,
+		"解像度":      cfg.Resolution,
+		"フレームレート":  fmt.Sprintf("%dfps", cfg.FPS),
+		"レイアウト":    cfg.Layout,
+		"切り抜き範囲":   formatClipRange(cfg.ClipStart, cfg.ClipEnd),
+		"エンド画面":    endScreenLabel,
+		"プロジェクト":   projectLabel,
+		"開始画面":     startScreenLabel,
+		"HUD連番画像":  overlayLabel,
+		"背景の派生画像":  variantsLabel,
+		"譜面プレビュー":  previewLabel,
+		"補間方法":     formatResample(cfg.Resample),
+		"汎用タイムライン": timelineLabel,
+		"タイミング":    formatTiming(cfg),
+		"難易度":      fmt.Sprintf("%v", cfg.ExtraData["difficulty"]),
+		"タイトル":     fmt.Sprintf("%v", cfg.ExtraData["title"]),
+		"作者":       fmt.Sprintf("%v", cfg.ExtraData["author"]),
+		"作詞":       formatCredit(opts.words, "-"),
+		"作曲":       formatCredit(opts.music, "-"),
+		"編曲":       formatCredit(opts.arrange, "-"),
+		"ボーカル":     formatCredit(opts.vocal, "Inst. ver."),
 	}
 	console.PrintKVTable(summary)
 
@@ -149,23 +326,472 @@ func runGeneration(console *ui.Console) {
 	console.PrintSuccess("処理が完了しました！")
 }
 
-func runSetup(console *ui.Console) {
+// generationOptions は詳細設定で変更できる生成設定
+type generationOptions struct {
+	words, music, arrange, vocal  string
+	jacketOverride, audioOverride string
+	bgSource                      modules.BackgroundSource
+	bgImage                       string
+	fps                           int
+	resolution, layout            string
+	clipStart, clipEnd            string
+	noEndScreen, exportAup2       bool
+	startScreen, jacketTheme      bool
+	fontPath                      string
+	renderOverlay                 bool
+	backgroundVariants            bool
+	chartPreview                  bool
+	noteSpeed                     float64
+	exportTimelines               bool
+	templateName                  string
+}
+
+// defaultGenerationOptions は詳細設定を変更しない場合の生成設定を返す
+// クレジットとローカルのファイルは、コマンドライン引数か自動取得した値を使う
+func defaultGenerationOptions(console *ui.Console, credits modules.Credits) generationOptions {
+	return generationOptions{
+		words:          defaultCredit("words", *wordsFlag, credits.Words),
+		music:          defaultCredit("music", *musicFlag, credits.Music),
+		arrange:        defaultCredit("arrange", *arrangeFlag, credits.Arrange),
+		vocal:          defaultCredit("vocal", *vocalFlag, credits.Vocal),
+		jacketOverride: checkOverridePath(console, "ジャケット画像", *jacketFlag),
+		audioOverride:  checkOverridePath(console, "音源(MP3/WAV/Ogg)", *audioFlag),
+		bgSource:       modules.BackgroundGenerated,
+		fps:            modules.BaseFPS,
+		resolution:     "1080p",
+		layout:         modules.LayoutLandscape,
+		exportAup2:     true,
+		noteSpeed:      modules.DefaultNoteSpeed,
+		templateName:   modules.DefaultAliasTemplate,
+	}
+}
+
+// readGenerationOptions は詳細設定を順に入力させる（空白の項目はoptsの値のまま）
+func readGenerationOptions(console *ui.Console, credits modules.Credits, opts *generationOptions) {
+	// クレジットの入力
+	opts.words = readCredit(console, "作詞者", "words", *wordsFlag, credits.Words)
+	opts.music = readCredit(console, "作曲者", "music", *musicFlag, credits.Music)
+	opts.arrange = readCredit(console, "編曲者", "arrange", *arrangeFlag, credits.Arrange)
+	opts.vocal = readCredit(console, "ボーカル (- でInst. ver.)", "vocal", *vocalFlag, credits.Vocal)
+
+	// ローカルのジャケット画像・音源の指定
+	opts.jacketOverride = readOverridePath(console, "ジャケット画像", "jacket", *jacketFlag)
+	opts.audioOverride = readOverridePath(console, "音源(MP3/WAV/Ogg)", "audio", *audioFlag)
+
+	// 背景の種類の選択
+	console.PrintInfo("背景の種類を選択してください (1: ジャケットから生成、2: 譜面に設定された背景、3: 画像ファイル、デフォルト: 1): ")
+	switch sourceInput := getUserChoice(console); sourceInput {
+	case "", "1":
+	case "2":
+		opts.bgSource = modules.BackgroundServer
+	case "3":
+		console.PrintInfo("背景に使う画像ファイルのパスを入力してください: ")
+		bgImage := strings.Trim(getUserChoice(console), "\"")
+		if _, err := os.Stat(bgImage); bgImage == "" || err != nil {
+			console.PrintError("画像ファイルが見つかりません。ジャケットから生成します。")
+		} else {
+			opts.bgSource = modules.BackgroundFile
+			opts.bgImage = bgImage
+		}
+	default:
+		console.PrintError("無効な選択です。ジャケットから生成します。")
+	}
+
+	// フレームレートの入力
+	console.PrintInfo("フレームレートを入力してください (30/60/120、デフォルト: 60): ")
+	if fpsInput := getUserChoice(console); fpsInput != "" {
+		if value, err := strconv.Atoi(fpsInput); err == nil && (value == 30 || value == 60 || value == 120) {
+			opts.fps = value
+		} else {
+			console.PrintError("無効なフレームレートです。デフォルト値(60)を使用します。")
+		}
+	}
+
+	// 解像度の入力
+	console.PrintInfo(fmt.Sprintf("解像度を入力してください (%s、デフォルト: 1080p): ", strings.Join(modules.ResolutionNames, "/")))
+	if resolution := strings.ToLower(getUserChoice(console)); resolution != "" {
+		if _, exists := modules.ResolutionPresets[resolution]; exists {
+			opts.resolution = resolution
+		} else {
+			console.PrintError("無効な解像度です。デフォルト値(1080p)を使用します。")
+		}
+	}
+
+	// レイアウトの選択
+	console.PrintInfo("レイアウトを選択してください (1: 横向き 16:9、2: 縦向き 9:16 ショート用、デフォルト: 1): ")
+	layoutInput := getUserChoice(console)
+	if layoutInput == "2" {
+		opts.layout = modules.LayoutPortrait
+	} else if layoutInput != "" && layoutInput != "1" {
+		console.PrintError("無効なレイアウトです。横向きを使用します。")
+	}
+
+	// 切り抜き範囲の入力
+	console.PrintInfo("切り抜き開始位置を入力してください (秒、または 128b のようにビート指定。空白で最初から): ")
+	opts.clipStart = readClipPoint(console)
+	console.PrintInfo("切り抜き終了位置を入力してください (秒、または 192b のようにビート指定。空白で最後まで): ")
+	opts.clipEnd = readClipPoint(console)
+
+	// エンド画面の有無
+	console.PrintInfo("エンド画面(AP演出など)を含めますか？ (Y/n): ")
+	endScreenInput := strings.ToLower(getUserChoice(console))
+	opts.noEndScreen = endScreenInput == "n" || endScreenInput == "no"
+
+	// プロジェクトファイル出力の有無
+	console.PrintInfo("AviUtl2のプロジェクトファイル(.aup2)も出力しますか？ (Y/n): ")
+	projectInput := strings.ToLower(getUserChoice(console))
+	opts.exportAup2 = projectInput != "n" && projectInput != "no"
+
+	// 開始画面の画像生成
+	console.PrintInfo("開始画面のタイトル・クレジットを画像で生成しますか？ (y/N): ")
+	startScreenInput := strings.ToLower(getUserChoice(console))
+	opts.startScreen = startScreenInput == "y" || startScreenInput == "yes"
+	if opts.startScreen {
+		console.PrintInfo("使用するフォントファイル(.otf/.ttf/.ttc)のパスを入力してください (空白でassets/fonts内または同梱フォント): ")
+		opts.fontPath = strings.Trim(getUserChoice(console), "\"")
+
+		console.PrintInfo("開始画面の背景の色をジャケットの配色に合わせますか？ (y/N): ")
+		themeInput := strings.ToLower(getUserChoice(console))
+		opts.jacketTheme = themeInput == "y" || themeInput == "yes"
+	}
+
+	// HUDの連番画像出力の有無
+	console.PrintInfo("AviUtl2以外の編集ソフト用に、HUDを透過PNGの連番でも出力しますか？ (y/N): ")
+	overlayInput := strings.ToLower(getUserChoice(console))
+	opts.renderOverlay = overlayInput == "y" || overlayInput == "yes"
+
+	// 背景の派生画像の出力の有無
+	console.PrintInfo("開始画面やエンド画面の後ろに置く、ぼかした背景・暗くした背景・ジャケットカードも出力しますか？ (y/N): ")
+	variantsInput := strings.ToLower(getUserChoice(console))
+	opts.backgroundVariants = variantsInput == "y" || variantsInput == "yes"
+
+	// 譜面プレビュー出力の有無
+	console.PrintInfo("レーンにノーツを流した譜面プレビューを透過PNGの連番で出力しますか？ (y/N): ")
+	previewInput := strings.ToLower(getUserChoice(console))
+	opts.chartPreview = previewInput == "y" || previewInput == "yes"
+	if opts.chartPreview {
+		console.PrintInfo(fmt.Sprintf("ノーツスピードを入力してください (%.1f〜%.1f、デフォルト: %.1f): ", modules.MinNoteSpeed, modules.MaxNoteSpeed, modules.DefaultNoteSpeed))
+		if speedInput := getUserChoice(console); speedInput != "" {
+			if speed, err := strconv.ParseFloat(speedInput, 64); err == nil && speed >= modules.MinNoteSpeed && speed <= modules.MaxNoteSpeed {
+				opts.noteSpeed = speed
+			} else {
+				console.PrintError("無効なノーツスピードです。デフォルト値を使用します。")
+			}
+		}
+	}
+
+	// 汎用タイムライン出力の有無
+	console.PrintInfo("Kdenlive/Shotcut(MLT)やDaVinci Resolve(FCPXML)用のタイムラインも出力しますか？ (y/N): ")
+	timelineInput := strings.ToLower(getUserChoice(console))
+	opts.exportTimelines = timelineInput == "y" || timelineInput == "yes"
+
+	// テンプレートの選択
+	opts.templateName = selectAliasTemplate(console)
+}
+
+// fetchCredits は譜面の詳細情報からクレジットを取得する
+// 取得できない場合は空のクレジットを返し、生成時にlevel.jsonから補完する
+func fetchCredits(console *ui.Console, levelID string) modules.Credits {
+	levelData, err := modules.FetchLevelDetails(levelID)
+	if err != nil {
+		console.PrintError(fmt.Sprintf("クレジットの自動取得に失敗しました: %v", err))
+		return modules.Credits{}
+	}
+	return modules.ExtractCredits(levelData)
+}
+
+// defaultCredit はコマンドライン引数で指定されたクレジットか、自動取得した値を返す
+func defaultCredit(flagName, flagValue, detected string) string {
+	if setFlags[flagName] {
+		return flagValue
+	}
+	return detected
+}
+
+// readCredit はクレジットを入力させる
+// コマンドライン引数で指定されている場合は入力を省略し、空白の場合は自動取得した値を使用する
+func readCredit(console *ui.Console, label, flagName, flagValue, detected string) string {
+	if setFlags[flagName] {
+		return flagValue
+	}
+
+	if detected != "" {
+		console.PrintInfo(fmt.Sprintf("%sを入力してください (空白で自動取得した値を使用: %s): ", label, detected))
+	} else {
+		console.PrintInfo(fmt.Sprintf("%sを入力してください (空白で未設定): ", label))
+	}
+	input := getUserChoice(console)
+	if input == "" {
+		return detected
+	}
+	return input
+}
+
+// readOverridePath はサーバーの素材の代わりに使うローカルのファイルのパスを取得する
+// コマンドライン引数で指定された場合は入力を省略し、ファイルがない場合はダウンロードした素材を使う
+func readOverridePath(console *ui.Console, label, flagName, flagValue string) string {
+	path := flagValue
+	if !setFlags[flagName] {
+		console.PrintInfo(fmt.Sprintf("ローカルの%sのパスを入力してください (空白でサーバーからダウンロード): ", label))
+		path = getUserChoice(console)
+	}
+	return checkOverridePath(console, label, path)
+}
+
+// checkOverridePath はローカルのファイルのパスを確認し、ファイルがない場合は空を返す
+func checkOverridePath(console *ui.Console, label, path string) string {
+	path = strings.Trim(path, "\"")
+	if path == "" {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		console.PrintError(fmt.Sprintf("%sが見つかりません。サーバーからダウンロードします: %s", label, path))
+		return ""
+	}
+	return path
+}
+
+// formatCredit はクレジットを表示用の文字列に変換する
+func formatCredit(value, fallback string) string {
+	if value == "" || value == "-" {
+		return fallback
+	}
+	return value
+}
+
+// formatTiming はタイミング設定を表示用の文字列に変換する
+func formatTiming(cfg config.Config) string {
+	values := []struct {
+		label string
+		value float64
+	}{
+		{"リードイン", cfg.LeadIn},
+		{"エンド画面まで", cfg.EndScreenDelay},
+		{"フェード開始", cfg.FadeDelay},
+		{"フェード", cfg.FadeDuration},
+		{"保持", cfg.FadeHold},
+	}
+
+	var parts []string
+	for _, v := range values {
+		if v.value >= 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2f秒", v.label, v.value))
+		}
+	}
+	if len(parts) == 0 {
+		return "自動"
+	}
+	return strings.Join(parts, ", ") + " (他は自動)"
+}
+
+// readClipPoint は切り抜き位置を入力させ、無効な値の場合は指定なしとして扱う
+func readClipPoint(console *ui.Console) string {
+	input := getUserChoice(console)
+	if _, err := modules.ParseClipPoint(input); err != nil {
+		console.PrintError(fmt.Sprintf("%v。指定なしとして扱います。", err))
+		return ""
+	}
+	return input
+}
+
+// formatClipRange は切り抜き範囲を表示用の文字列に変換する
+func formatClipRange(start, end string) string {
+	if start == "" && end == "" {
+		return "なし"
+	}
+	startPoint, _ := modules.ParseClipPoint(start)
+	endPoint, _ := modules.ParseClipPoint(end)
+	return fmt.Sprintf("%s - %s", startPoint, endPoint)
+}
+
+// selectAliasTemplate はエイリアステンプレートを選択させる
+func selectAliasTemplate(console *ui.Console) string {
+	templates, err := modules.ListAliasTemplates()
+	if err != nil {
+		console.PrintError(fmt.Sprintf("テンプレート一覧の取得に失敗しました: %v", err))
+		return modules.DefaultAliasTemplate
+	}
+	if len(templates) <= 1 {
+		return modules.DefaultAliasTemplate
+	}
+
+	console.PrintInfo(fmt.Sprintf("テンプレートを選択してください (ユーザーテンプレート: %s)", modules.GetUserTemplateDir()))
+	for i, tmpl := range templates {
+		label := tmpl.Name
+		if tmpl.IsUser {
+			label += " (ユーザー)"
+		}
+		fmt.Printf("%d. %s\n", i+1, label)
+	}
+	console.PrintInfo(fmt.Sprintf("番号を入力してください (デフォルト: %s): ", modules.DefaultAliasTemplate))
+
+	input := getUserChoice(console)
+	if input == "" {
+		return modules.DefaultAliasTemplate
+	}
+	if index, err := strconv.Atoi(input); err == nil && index >= 1 && index <= len(templates) {
+		return templates[index-1].Name
+	}
+	console.PrintError("無効な選択です。デフォルトのテンプレートを使用します。")
+	return modules.DefaultAliasTemplate
+}
+
+// runSetup はスクリプトディレクトリを選んでスクリプトをインストールする（キャンセル・失敗した場合はfalseを返す）
+func runSetup(console *ui.Console) bool {
 	console.PrintHeader("セットアップ")
 
 	console.PrintInfo("セットアップを開始します...")
-	console.PrintInfo("この処理には管理者権限が必要になる場合があります。")
+	scriptDir, ok := selectScriptDir(console)
+	if !ok {
+		console.PrintInfo("セットアップをキャンセルしました。")
+		return false
+	}
+
+	console.PrintInfo(fmt.Sprintf("'%s' に@SekaiObjects.obj2, unmult.anm2, dkjson.luaを書き込みます。", scriptDir))
+	if err := modules.CheckScriptDir(scriptDir); err != nil {
+		console.PrintError(err.Error())
+		return false
+	}
 	console.PrintInfo("続行しますか？ (y/N): ")
 
 	choice := getUserChoice(console)
 	if strings.ToLower(choice) != "y" && strings.ToLower(choice) != "yes" {
 		console.PrintInfo("セットアップをキャンセルしました。")
-		return
+		return false
+	}
+
+	if err := modules.SaveScriptDir(scriptDir); err != nil {
+		console.PrintError(err.Error())
+		return false
+	}
+
+	// ロールバックした後は、確認してから再インストールする
+	if modules.ScriptsRolledBack() {
+		console.PrintInfo("前回ロールバックしたスクリプトを最新のバージョンで置き換えますか？ (y/N): ")
+		choice := strings.ToLower(getUserChoice(console))
+		if choice != "y" && choice != "yes" {
+			console.PrintInfo("ロールバックしたスクリプトをそのまま使います。")
+			return true
+		}
+		if err := modules.ClearScriptRollback(); err != nil {
+			console.PrintError(err.Error())
+			return false
+		}
 	}
 
 	if err := modules.CheckAndRunSetup(); err != nil {
 		console.PrintError(fmt.Sprintf("セットアップに失敗しました: %v", err))
-		return
+		return false
 	}
 
 	console.PrintSuccess("セットアップが完了しました。")
+	return true
+}
+
+// runCommand はコマンドライン引数で指定されたコマンドを実行する
+func runCommand(console *ui.Console, command string) bool {
+	switch command {
+	case "setup":
+		return runSetup(console)
+	case "uninstall":
+		return runUninstall(console, false)
+	case "rollback":
+		return runRollback(console)
+	}
+	console.PrintError(fmt.Sprintf("不明なコマンドです: %s (setup/uninstall/rollback)", command))
+	return false
+}
+
+// runUninstall はインストールしたスクリプトを削除する（confirmがtrueの場合は確認する）
+func runUninstall(console *ui.Console, confirm bool) bool {
+	console.PrintHeader("アンインストール")
+
+	console.PrintInfo(fmt.Sprintf("'%s' から@SekaiObjects.obj2, unmult.anm2, dkjson.luaを削除します。", config.AviUtlScriptDir))
+	if confirm {
+		console.PrintInfo("続行しますか？ (y/N): ")
+		choice := strings.ToLower(getUserChoice(console))
+		if choice != "y" && choice != "yes" {
+			console.PrintInfo("アンインストールをキャンセルしました。")
+			return false
+		}
+	}
+
+	if err := modules.UninstallScripts(); err != nil {
+		console.PrintError(fmt.Sprintf("アンインストールに失敗しました: %v", err))
+		return false
+	}
+	console.PrintSuccess("アンインストールが完了しました。ロールバックで元に戻せます。")
+	return true
+}
+
+// runRollback は最新のバックアップからスクリプトを復元する
+func runRollback(console *ui.Console) bool {
+	console.PrintHeader("ロールバック")
+
+	if err := modules.RollbackScripts(); err != nil {
+		console.PrintError(fmt.Sprintf("ロールバックに失敗しました: %v", err))
+		return false
+	}
+	console.PrintSuccess("ロールバックが完了しました。")
+	return true
+}
+
+// selectScriptDir はセットアップ先のスクリプトディレクトリを選択させる
+// 空白の場合は現在のディレクトリを使い、番号以外の入力はパスとして扱う
+func selectScriptDir(console *ui.Console) (string, bool) {
+	candidates := modules.DetectScriptDirs()
+	console.PrintInfo("AviUtl2のスクリプトディレクトリを選択してください:")
+	for i, candidate := range candidates {
+		status := ""
+		if !candidate.Exists {
+			status = " (見つかりません)"
+		}
+		fmt.Printf("%d. %s [%s]%s\n", i+1, candidate.Path, candidate.Label, status)
+	}
+	console.PrintInfo(fmt.Sprintf("番号またはパスを入力してください (空白で現在のディレクトリ: %s、qでキャンセル): ", config.AviUtlScriptDir))
+
+	input := strings.Trim(getUserChoice(console), "\"")
+	switch {
+	case input == "":
+		return config.AviUtlScriptDir, true
+	case strings.ToLower(input) == "q":
+		return "", false
+	}
+	if index, err := strconv.Atoi(input); err == nil {
+		if index >= 1 && index <= len(candidates) {
+			return candidates[index-1].Path, true
+		}
+		console.PrintError("無効な番号です。")
+		return "", false
+	}
+	return filepath.Clean(input), true
+}
+
+// formatResample は補間方法を表示用の文字列に変換する
+func formatResample(name string) string {
+	filter, err := modules.ParseResampleFilter(name)
+	if err != nil {
+		return name
+	}
+	return string(filter)
+}
+
+// formatBackground は背景の取得方法を表示用の文字列に変換する
+func formatBackground(cfg config.Config) string {
+	source, _ := modules.ParseBackgroundSource(cfg.BgSource)
+	switch source {
+	case modules.BackgroundServer:
+		return fmt.Sprintf("譜面に設定された背景 (ない場合は%sで生成)", cfg.BgVersion)
+	case modules.BackgroundFile:
+		return fmt.Sprintf("画像ファイル (%s)", cfg.BgImage)
+	}
+	return fmt.Sprintf("ジャケットから生成 (%s)", cfg.BgVersion)
+}
+
+// formatOverride はローカルのファイルの指定を表示用の文字列に変換する
+func formatOverride(path string) string {
+	if path == "" {
+		return "サーバーからダウンロード"
+	}
+	return fmt.Sprintf("ローカルのファイル (%s)", path)
 }
@@ -3,14 +3,18 @@ module sekai-overlay-go
 go 1.24.0
 
 require (
-	github.com/disintegration/imaging v1.6.2 // indirect
-	github.com/fatih/color v1.18.0 // indirect
+	github.com/disintegration/imaging v1.6.2
+	github.com/fatih/color v1.18.0
+	golang.org/x/image v0.32.0
+	gopkg.in/ini.v1 v1.67.0
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/image v0.32.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
-	gopkg.in/ini.v1 v1.67.0 // indirect
+	golang.org/x/text v0.30.0 // indirect
 )
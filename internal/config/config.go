@@ -14,11 +14,48 @@ const (
 
 // Config はアプリケーション設定を保持する構造体
 type Config struct {
-	FullLevelID string                 `json:"full_level_id"`
-	BgVersion   string                 `json:"bg_version"`
-	TeamPower   float64                `json:"team_power"`
-	AppVersion  string                 `json:"app_version"`
-	ExtraData   map[string]interface{} `json:"extra_data"`
+	FullLevelID string  `json:"full_level_id"`
+	BgVersion   string  `json:"bg_version"`
+	TeamPower   float64 `json:"team_power"`
+	Template    string  `json:"template"`
+	FPS         int     `json:"fps"`
+	Resolution  string  `json:"resolution"`
+	Layout      string  `json:"layout"`
+	ClipStart   string  `json:"clip_start"`
+	ClipEnd     string  `json:"clip_end"`
+	NoEndScreen bool    `json:"no_end_screen"`
+	ExportAup2  bool    `json:"export_aup2"`
+	StartScreen bool    `json:"start_screen"`
+	FontPath    string  `json:"font_path"`
+	// HUDを透過PNGの連番でも出力する
+	RenderOverlay bool `json:"render_overlay"`
+	// MLT XML・FCPXMLのタイムラインも出力する
+	ExportTimelines bool `json:"export_timelines"`
+	// 譜面のプレビュー（レーンとノーツ）を透過PNGの連番で出力する
+	ChartPreview bool    `json:"chart_preview"`
+	NoteSpeed    float64 `json:"note_speed"`
+	// 背景画像の取得方法（generated/server/file）と、fileの場合の画像のパス
+	BgSource string `json:"bg_source"`
+	BgImage  string `json:"bg_image"`
+	// サーバーの代わりに使うローカルのジャケット画像・音源（空の場合はダウンロードする）
+	JacketOverride string `json:"jacket_override"`
+	AudioOverride  string `json:"audio_override"`
+	// ジャケットから抽出した色を開始画面に使う
+	JacketTheme bool `json:"jacket_theme"`
+	// ぼかし・暗くした背景とジャケットカードも出力する（0の場合はデフォルト値）
+	BackgroundVariants bool    `json:"background_variants"`
+	BlurRadius         float64 `json:"blur_radius"`
+	DimBrightness      float64 `json:"dim_brightness"`
+	// 背景のジャケットの補間方法（bilinear/bicubic/lanczos、空の場合はデフォルト）
+	Resample string `json:"resample"`
+	// 開始・終了演出のタイミング（秒、負の値の場合は自動）
+	LeadIn         float64                `json:"lead_in"`
+	EndScreenDelay float64                `json:"end_screen_delay"`
+	FadeDelay      float64                `json:"fade_delay"`
+	FadeDuration   float64                `json:"fade_duration"`
+	FadeHold       float64                `json:"fade_hold"`
+	AppVersion     string                 `json:"app_version"`
+	ExtraData      map[string]interface{} `json:"extra_data"`
 }
 
 // GetConfigDir は設定ディレクトリのパスを取得する
@@ -48,6 +85,13 @@ var UnmultAnmURL = "https://gist.githubusercontent.com/mes51/f90331af552231f39ad
 // DkjsonLuaURL はdkjson.luaのダウンロードURL
 var DkjsonLuaURL = "https://raw.githubusercontent.com/LuaDist/dkjson/refs/heads/master/dkjson.lua"
 
+// UnmultAnmSHA256・DkjsonLuaSHA256 はダウンロードしたファイルと同梱のファイルを検証するSHA-256
+// assets/scripts/vendorに同梱するファイルと同じ値にする（空の場合はダウンロードしたファイルを検証せずに使い、同梱のファイルは使わない）
+var (
+	UnmultAnmSHA256 = ""
+	DkjsonLuaSHA256 = ""
+)
+
 // ServerMap はサーバーURLマッピング
 var ServerMap = map[string]string{
 	"chcy":               "https://cc.sevenc7c.com/sonolus/levels/",
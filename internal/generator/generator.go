@@ -47,6 +47,33 @@ func (g *Generator) Run() error {
 	idPart := parts[len(parts)-1]
 	fullLevelID = fmt.Sprintf("%s-%s", prefix, idPart)
 
+	// 出力フォーマットの検証
+	format, err := modules.NewVideoFormat(g.config.FPS, g.config.Resolution, g.config.Layout)
+	if err != nil {
+		return err
+	}
+
+	// 切り抜き範囲の検証
+	clipStart, err := modules.ParseClipPoint(g.config.ClipStart)
+	if err != nil {
+		return err
+	}
+	clipEnd, err := modules.ParseClipPoint(g.config.ClipEnd)
+	if err != nil {
+		return err
+	}
+	resample, err := modules.ParseResampleFilter(g.config.Resample)
+	if err != nil {
+		return err
+	}
+	bgSource, err := modules.ParseBackgroundSource(g.config.BgSource)
+	if err != nil {
+		return err
+	}
+	if bgSource == modules.BackgroundFile && g.config.BgImage == "" {
+		return fmt.Errorf("背景の画像ファイルが指定されていません")
+	}
+
 	// 出力先ディレクトリの作成
 	distDir := filepath.Join(g.appRoot, "dist", fullLevelID)
 	if err := os.MkdirAll(distDir, 0755); err != nil {
@@ -55,31 +82,121 @@ func (g *Generator) Run() error {
 
 	// 1. ダウンロード
 	g.console.PrintStatus(fmt.Sprintf("[%s] データをダウンロード中...", fullLevelID))
-	levelID, err := modules.DownloadAndPrepareAssets(prefix, idPart, distDir)
+	overrides := modules.AssetOverrides{Jacket: g.config.JacketOverride, Audio: g.config.AudioOverride}
+	levelID, sources, err := modules.DownloadAndPrepareAssets(prefix, idPart, distDir, overrides)
 	if err != nil {
 		return fmt.Errorf("データダウンロードに失敗しました: %w", err)
 	}
 
+	// ジャケットの配色の抽出（失敗してもテーマカラーなしで生成を続ける）
+	var theme *modules.ThemeColors
+	palette, err := modules.LoadJacketPalette(distDir)
+	if err != nil {
+		g.console.PrintError(fmt.Sprintf("ジャケットの配色の抽出に失敗しました: %v", err))
+	} else {
+		theme = palette.Theme()
+		fmt.Printf("テーマカラー: %s (アクセント: %s)\n", theme.Dominant, theme.Accent)
+	}
+
 	// 2. 背景画像生成
 	g.console.PrintStatus("背景画像を生成中...")
-	if err := modules.GenerateBackgroundImage(levelID, g.config.BgVersion, distDir); err != nil {
+	background, err := g.generateBackground(bgSource, prefix, levelID, distDir, format, resample)
+	if err != nil {
 		return fmt.Errorf("背景画像生成に失敗しました: %w", err)
 	}
 
+	// 開始画面の生成
+	if g.config.StartScreen {
+		g.console.PrintStatus("開始画面を生成中...")
+		info, err := modules.LoadSongInfo(distDir, g.config.ExtraData)
+		if err != nil {
+			return fmt.Errorf("開始画面生成に失敗しました: %w", err)
+		}
+		startOpts := modules.StartScreenOptions{
+			Format:         format,
+			FontPath:       g.config.FontPath,
+			BackgroundZoom: modules.BackgroundZoom(modules.BackgroundSource(background.Source), format),
+		}
+		if g.config.JacketTheme && theme != nil {
+			startOpts.Theme = &palette
+		}
+		if err := modules.GenerateStartScreen(distDir, info, startOpts); err != nil {
+			return fmt.Errorf("開始画面生成に失敗しました: %w", err)
+		}
+	}
+
+	// BGMの解析（失敗しても譜面の情報だけで生成を続ける）
+	g.console.PrintStatus("BGMを解析中...")
+	audio, err := modules.LoadAudioInfo(modules.MusicPath(distDir))
+	if err != nil {
+		g.console.PrintError(fmt.Sprintf("BGMの解析に失敗しました。譜面の情報から長さを決定します: %v", err))
+	} else {
+		fmt.Printf("BGM: %s\n", audio)
+	}
+
 	// 3. スコアオブジェクト生成
 	g.console.PrintStatus("スコアオブジェクトを生成中...")
-	lastNoteTime, err := modules.GenerateSkobjData(levelID, distDir, g.config.TeamPower, g.config.AppVersion)
+	timing, err := modules.GenerateSkobjData(levelID, distDir, g.config.TeamPower, g.config.AppVersion, audio, theme)
 	if err != nil {
 		return fmt.Errorf("スコアオブジェクト生成に失敗しました: %w", err)
 	}
 
 	// 4. エイリアスオブジェクト生成
 	g.console.PrintStatus("エイリアスオブジェクトを生成中...")
-	title, err := modules.GenerateAliasObject(levelID, distDir, lastNoteTime, g.config.ExtraData)
+	aliasOpts := modules.AliasOptions{
+		Template:        g.config.Template,
+		Format:          format,
+		Clip:            modules.ClipRange{Start: clipStart, End: clipEnd},
+		SkipEndScreen:   g.config.NoEndScreen,
+		ExportProject:   g.config.ExportAup2,
+		StartScreenText: g.config.StartScreen,
+		Audio:           audio,
+		ExportTimelines: g.config.ExportTimelines,
+		TimelineOverlay: g.config.RenderOverlay,
+		TimelinePreview: g.config.ChartPreview,
+		BackgroundZoom:  modules.BackgroundZoom(modules.BackgroundSource(background.Source), format),
+		Timing: modules.TimingSettings{
+			LeadIn:         g.config.LeadIn,
+			EndScreenDelay: g.config.EndScreenDelay,
+			FadeDelay:      g.config.FadeDelay,
+			FadeDuration:   g.config.FadeDuration,
+			FadeHold:       g.config.FadeHold,
+		},
+	}
+	title, err := modules.GenerateAliasObject(levelID, distDir, timing, g.config.ExtraData, aliasOpts)
 	if err != nil {
 		return fmt.Errorf("エイリアスオブジェクト生成に失敗しました: %w", err)
 	}
 
+	// HUDの連番画像生成
+	if g.config.RenderOverlay {
+		g.console.PrintStatus("HUDの連番画像を生成中...")
+		if err := modules.RenderOverlaySequence(distDir, modules.OverlayOptions{Format: format}); err != nil {
+			return fmt.Errorf("HUDの連番画像生成に失敗しました: %w", err)
+		}
+	}
+
+	// 譜面プレビューの連番画像生成（chart.jsonを使うのでクリーンアップ前に行う）
+	if g.config.ChartPreview {
+		g.console.PrintStatus("譜面プレビューの連番画像を生成中...")
+		previewOpts := modules.ChartPreviewOptions{Format: format, NoteSpeed: g.config.NoteSpeed}
+		if err := modules.RenderChartPreview(distDir, previewOpts); err != nil {
+			return fmt.Errorf("譜面プレビューの連番画像生成に失敗しました: %w", err)
+		}
+	}
+
+	// 生成情報の出力（ローカルのファイルで置き換えた素材も記録する）
+	manifest := modules.OutputManifest{
+		LevelID:    levelID,
+		AppVersion: g.config.AppVersion,
+		Jacket:     sources.Jacket,
+		Audio:      sources.Audio,
+		Background: background,
+	}
+	if err := modules.WriteManifest(distDir, manifest); err != nil {
+		return err
+	}
+
 	// 5. クリーンアップ
 	g.cleanup(distDir)
 
@@ -95,7 +212,7 @@ func (g *Generator) Run() error {
 func (g *Generator) cleanup(distDir string) {
 	g.console.PrintStatus("一時ファイルをクリーンアップ中...")
 
-	filesToRemove := []string{"level.json", "chart.json"}
+	filesToRemove := []string{"level.json", "chart.json", "background_source"}
 	for _, filename := range filesToRemove {
 		path := filepath.Join(distDir, filename)
 		if _, err := os.Stat(path); err == nil {
@@ -113,3 +230,36 @@ func (g *Generator) openOutputFolder(path string) {
 		fmt.Printf("出力フォルダを自動で開けませんでした: %v\n", err)
 	}
 }
+
+// generateBackground は選択された取得方法で背景画像を用意し、実際に使った取得方法を返す
+// 譜面に背景が設定されていない場合は、ジャケットから生成した背景を使う
+func (g *Generator) generateBackground(source modules.BackgroundSource, prefix, levelID, distDir string, format modules.VideoFormat, resample modules.ResampleFilter) (modules.ManifestBackground, error) {
+	variants := modules.BackgroundVariants{
+		Enabled:    g.config.BackgroundVariants,
+		BlurRadius: g.config.BlurRadius,
+		Brightness: g.config.DimBrightness,
+	}
+
+	switch source {
+	case modules.BackgroundFile:
+		used := modules.ManifestBackground{Source: string(source), Image: g.config.BgImage}
+		return used, modules.GenerateBackgroundFromImage(g.config.BgImage, distDir, format, variants)
+	case modules.BackgroundServer:
+		imagePath, err := modules.DownloadLevelBackground(prefix, distDir)
+		if err == nil {
+			return modules.ManifestBackground{Source: string(source)}, modules.GenerateBackgroundFromImage(imagePath, distDir, format, variants)
+		}
+		g.console.PrintError(fmt.Sprintf("譜面の背景を使用できません。ジャケットから生成します: %v", err))
+	}
+
+	used := modules.ManifestBackground{Source: string(modules.BackgroundGenerated), Layout: g.config.BgVersion}
+	if err := modules.GenerateBackgroundImage(levelID, g.config.BgVersion, distDir, resample, variants); err != nil {
+		return used, err
+	}
+	if format.IsPortrait() {
+		if err := modules.GeneratePortraitBackground(distDir); err != nil {
+			return used, fmt.Errorf("縦向き背景画像生成に失敗しました: %w", err)
+		}
+	}
+	return used, nil
+}
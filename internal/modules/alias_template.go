@@ -0,0 +1,169 @@
+package modules
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"sekai-overlay-go/internal/config"
+	"sekai-overlay-go/internal/utils"
+)
+
+// DefaultAliasTemplate は標準で使用するエイリアステンプレート名
+const DefaultAliasTemplate = "template"
+
+// AliasTemplate は選択可能なエイリアステンプレートを表す構造体
+type AliasTemplate struct {
+	Name   string
+	Path   string
+	IsUser bool
+}
+
+// GetUserTemplateDir はユーザーテンプレートディレクトリのパスを取得する
+func GetUserTemplateDir() string {
+	return filepath.Join(config.GetConfigDir(), "templates")
+}
+
+// ListAliasTemplates は同梱テンプレートとユーザーテンプレートの一覧を取得する
+// 同名のテンプレートがある場合はユーザーテンプレートを優先する
+func ListAliasTemplates() ([]AliasTemplate, error) {
+	templates := map[string]AliasTemplate{}
+
+	dirs := []struct {
+		path   string
+		isUser bool
+	}{
+		{utils.ResourcePath("assets/alias"), false},
+		{GetUserTemplateDir(), true},
+	}
+
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir.path, "*.object"))
+		if err != nil {
+			return nil, fmt.Errorf("テンプレートの検索に失敗しました: %w", err)
+		}
+		for _, path := range matches {
+			name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			templates[name] = AliasTemplate{Name: name, Path: path, IsUser: dir.isUser}
+		}
+	}
+
+	result := make([]AliasTemplate, 0, len(templates))
+	for _, tmpl := range templates {
+		result = append(result, tmpl)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// FindAliasTemplate は名前からテンプレートを検索する
+func FindAliasTemplate(name string) (AliasTemplate, error) {
+	if name == "" {
+		name = DefaultAliasTemplate
+	}
+
+	templates, err := ListAliasTemplates()
+	if err != nil {
+		return AliasTemplate{}, err
+	}
+	for _, tmpl := range templates {
+		if tmpl.Name == name {
+			return tmpl, nil
+		}
+	}
+	return AliasTemplate{}, fmt.Errorf("テンプレート '%s' が見つかりません", name)
+}
+
+// aliasTemplateFuncs はテンプレート内で使用できるヘルパー関数
+func aliasTemplateFuncs(format VideoFormat) template.FuncMap {
+	return template.FuncMap{
+		// frame は秒数をフレーム数に変換する
+		"frame": format.Frame,
+		// f は60fps基準のフレーム数を出力フレームレートに変換する
+		"f": format.Frames,
+		// fend は60fps基準の終了フレームを出力フレームレートに変換する
+		"fend": format.FrameEnd,
+		// pos は1080p基準の座標・サイズを出力解像度に変換する
+		"pos": func(value float64) string {
+			return strconv.FormatFloat(format.Pos(value), 'f', 2, 64)
+		},
+		// zoom は1080p基準の拡大率を出力解像度に変換する
+		"zoom": func(value float64) string {
+			return strconv.FormatFloat(format.Pos(value), 'f', 3, 64)
+		},
+		"add": func(a, b int) int { return a + b },
+		"sub": func(a, b int) int { return a - b },
+		"mul": func(a, b int) int { return a * b },
+		"max": func(a, b int) int {
+			if a > b {
+				return a
+			}
+			return b
+		},
+		// escape はテキストオブジェクト用に改行をエスケープする
+		"escape": escapeObjectText,
+		// default は値が空の場合に既定値を返す
+		"default": func(def, value string) string {
+			if value == "" {
+				return def
+			}
+			return value
+		},
+	}
+}
+
+// escapeObjectText は.objectファイルの値として書き出せるように改行をエスケープする
+func escapeObjectText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// executeAliasTemplate はテンプレートを読み込み、データを埋め込んだ.objectの文字列を返す
+// 未定義の変数を参照した場合はエラーになる
+func executeAliasTemplate(tmpl AliasTemplate, data map[string]interface{}, format VideoFormat) (string, error) {
+	content, err := os.ReadFile(tmpl.Path)
+	if err != nil {
+		return "", fmt.Errorf("テンプレートファイルの読み込みに失敗しました: %w", err)
+	}
+
+	t, err := template.New(tmpl.Name).
+		Funcs(aliasTemplateFuncs(format)).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("テンプレート '%s' の解析に失敗しました: %w", tmpl.Name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("テンプレート '%s' の展開に失敗しました: %w", tmpl.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// renderAliasTemplate はテンプレートを展開した.objectを解析して返す
+// 展開結果が正しい.objectの形式でない場合はエラーになる
+func renderAliasTemplate(tmpl AliasTemplate, data map[string]interface{}, format VideoFormat) (*ObjectFile, error) {
+	content, err := executeAliasTemplate(tmpl, data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// 条件分岐で省略されたオブジェクトがあってもセクション番号は書き出し時に振り直される
+	objectFile, err := ParseObjectFile(content)
+	if err != nil {
+		return nil, fmt.Errorf("テンプレート '%s' の展開結果を解析できません: %w", tmpl.Name, err)
+	}
+	if err := objectFile.Validate(); err != nil {
+		return nil, fmt.Errorf("テンプレート '%s' の展開結果が不正です: %w", tmpl.Name, err)
+	}
+	return objectFile, nil
+}
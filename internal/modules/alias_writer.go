@@ -3,7 +3,6 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,39 +10,184 @@ import (
 	"sekai-overlay-go/internal/utils"
 )
 
+// AliasOptions はエイリアスオブジェクト生成のオプション
+type AliasOptions struct {
+	Template      string
+	Format        VideoFormat
+	Clip          ClipRange
+	SkipEndScreen bool
+	ExportProject bool
+	// 開始画面のテキストを生成済みの画像(start_text_*.png)で表示する
+	StartScreenText bool
+	// Timing は開始・終了演出のタイミング設定（負の値の項目は自動で決定する）
+	Timing TimingSettings
+	// Audio はBGMの解析結果（長さが0の場合は不明として扱う）
+	Audio AudioInfo
+	// ExportTimelines はMLT XMLとFCPXMLのタイムラインも出力する
+	ExportTimelines bool
+	// TimelineOverlay はタイムラインにHUDの連番画像(overlay/)を配置する
+	TimelineOverlay bool
+	// TimelinePreview はタイムラインの譜面プレビューの連番画像(preview/)をレーンの代わりに配置する
+	TimelinePreview bool
+	// BackgroundZoom は背景画像の拡大率（1080p基準、0の場合はテンプレートの値を使う）
+	BackgroundZoom float64
+}
+
 // GenerateAliasObject はエイリアスオブジェクトを生成する
-func GenerateAliasObject(levelID, distDir string, lastNoteTime float64, extraData map[string]interface{}) (string, error) {
+func GenerateAliasObject(levelID, distDir string, timing ChartTiming, extraData map[string]interface{}, opts AliasOptions) (string, error) {
 	fmt.Println("エイリアスオブジェクトの生成を開始します...")
 
-	// テンプレートファイルのパスを取得
-	templatePath := utils.ResourcePath("assets/alias/template.object")
-	levelJSONPath := filepath.Join(distDir, "level.json")
+	// 切り抜き範囲を秒に変換
+	clipStart, clipEnd, err := opts.Clip.Resolve(timing)
+	if err != nil {
+		return "", err
+	}
+	if opts.Clip.IsSet() {
+		fmt.Printf("切り抜き範囲: %.3f秒 - %.3f秒\n", clipStart, clipEnd)
+	}
+
+	// 開始位置が指定されていない場合、最初のノーツより前の長い無音区間は切り抜きと同様に飛ばす
+	if !opts.Clip.Start.Set && opts.Audio.LeadingSilence >= minSkippedSilence && opts.Audio.LeadingSilence < timing.FirstNoteTime {
+		clipStart = opts.Audio.LeadingSilence
+		fmt.Printf("先頭の無音区間 (%.3f秒) を飛ばします。\n", clipStart)
+	}
+
+	// テンプレートを検索
+	tmpl, err := FindAliasTemplate(opts.Template)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("テンプレート '%s' を使用します。\n", tmpl.Name)
+
 	outputPath := filepath.Join(distDir, "main.object")
 
-	// テンプレートファイルを読み込み
-	templateContent, err := os.ReadFile(templatePath)
+	// テンプレート用の値を取得
+	info, err := LoadSongInfo(distDir, extraData)
 	if err != nil {
-		return "", fmt.Errorf("テンプレートファイルの読み込みに失敗しました: %w", err)
+		return "", err
 	}
 
-	// level.jsonを読み込み
-	levelFile, err := os.Open(levelJSONPath)
+	// テンプレートデータを作成
+	data := map[string]interface{}{
+		"levelID":        levelID,
+		"title":          info.Title,
+		"author":         info.Author,
+		"words":          info.Words,
+		"music":          info.Music,
+		"arrange":        info.Arrange,
+		"vocal":          info.Vocal,
+		"difficulty":     strings.ToUpper(info.Difficulty),
+		"difficulty_img": info.DifficultyImg,
+		"rating":         info.Rating,
+	}
+
+	// パス情報
+	data["distPath"] = utils.WindowsPath(distDir)
+	data["assetsPath"] = utils.WindowsPath(utils.ResourcePath("assets"))
+	data["jacketFile"] = filepath.Base(jacketImagePath(distDir))
+	data["musicFile"] = filepath.Base(MusicPath(distDir))
+
+	// タイミング設定を決定
+	settings := ResolveTimingSettings(opts.Timing, timing, clipStart, clipEnd, opts.Clip.End.Set, opts.Audio.AudibleEnd())
+	fmt.Printf("タイミング: %s\n", settings)
+
+	// フレーム計算
+	// 切り抜き時は開始位置がBGMの開始フレームに来るように、InitSettingsのオフセットを前にずらす
+	format := opts.Format
+	hudStartFrame := format.Frames(startScreenBaseFrames)
+	leadInFrames := format.Frame(settings.LeadIn)
+	musicStartFrame := hudStartFrame + leadInFrames
+	scoreOffset := leadInFrames - format.Frame(clipStart)
+	videoStartFrame := format.Frame(clipEnd-clipStart+settings.EndScreenDelay) + musicStartFrame
+	fadeStartFrame := videoStartFrame + format.Frame(settings.FadeDelay)
+	fadeStopFrame := fadeStartFrame + format.Frame(settings.FadeDuration)
+	endFrame := fadeStopFrame + format.Frame(settings.FadeHold)
+	if opts.SkipEndScreen {
+		endFrame = videoStartFrame
+	}
+
+	data["fps"] = format.FPS
+	data["width"] = format.Width
+	data["height"] = format.Height
+	data["layout"] = format.Layout
+	data["backgroundZoom"] = opts.BackgroundZoom
+	data["lastNoteTime"] = timing.LastNoteTime
+	data["clipStart"] = clipStart
+	data["clipEnd"] = clipEnd
+	data["musicStart"] = fmt.Sprintf("%.3f", clipStart)
+	data["scoreOffset"] = scoreOffset
+	data["hudStartFrame"] = hudStartFrame
+	data["musicStartFrame"] = musicStartFrame
+	data["endScreen"] = !opts.SkipEndScreen
+	data["startScreenText"] = opts.StartScreenText
+	data["videoStartFrame"] = videoStartFrame
+	data["fadeStartFrame"] = fadeStartFrame
+	data["fadeStopFrame"] = fadeStopFrame
+	data["endFrame"] = endFrame
+
+	// テンプレートを展開
+	objectFile, err := renderAliasTemplate(tmpl, data, format)
 	if err != nil {
-		return "", fmt.Errorf("level.jsonの読み込みに失敗しました: %w", err)
+		return "", err
+	}
+
+	// 結果を書き出し
+	if err := os.WriteFile(outputPath, []byte(objectFile.String()), 0644); err != nil {
+		return "", fmt.Errorf("出力ファイルの書き込みに失敗しました: %w", err)
+	}
+
+	fmt.Printf("エイリアスオブジェクトを '%s' に保存しました。\n", outputPath)
+
+	// プロジェクトファイルを書き出し
+	if opts.ExportProject {
+		if err := writeProjectFile(distDir, data["distPath"].(string), objectFile, format); err != nil {
+			return "", err
+		}
+	}
+
+	// 汎用タイムラインを書き出し
+	if opts.ExportTimelines {
+		paths := objectPathMap{
+			distPath:   data["distPath"].(string),
+			distDir:    distDir,
+			assetsPath: data["assetsPath"].(string),
+			assetsDir:  utils.ResourcePath("assets"),
+		}
+		if err := writeTimelineFiles(distDir, info.Title, objectFile, format, paths, timelineSequences{overlay: opts.TimelineOverlay, preview: opts.TimelinePreview}); err != nil {
+			return "", err
+		}
+	}
+	return info.Title, nil
+}
+
+// SongInfo は開始画面やテキストに表示する楽曲情報を表す構造体
+type SongInfo struct {
+	Title         string
+	Author        string
+	Words         string
+	Music         string
+	Arrange       string
+	Vocal         string
+	Difficulty    string
+	DifficultyImg string
+	Rating        float64
+}
+
+// LoadSongInfo はlevel.jsonと入力値から楽曲情報を取得する（入力値を優先する）
+func LoadSongInfo(distDir string, extraData map[string]interface{}) (SongInfo, error) {
+	levelFile, err := os.Open(filepath.Join(distDir, "level.json"))
+	if err != nil {
+		return SongInfo{}, fmt.Errorf("level.jsonの読み込みに失敗しました: %w", err)
 	}
 	defer levelFile.Close()
 
 	var levelData map[string]interface{}
 	if err := json.NewDecoder(levelFile).Decode(&levelData); err != nil {
-		return "", fmt.Errorf("level.jsonの解析に失敗しました: %w", err)
+		return SongInfo{}, fmt.Errorf("level.jsonの解析に失敗しました: %w", err)
 	}
 
-	// プレースホルダー用の値を取得
 	itemData, _ := levelData["item"].(map[string]interface{})
 
-	finalTitle := getStringValue(extraData, "title", itemData, "title", "-")
-	finalAuthor := getStringValue(extraData, "author", itemData, "author", "-")
-
 	difficultyInput := getStringValue(extraData, "difficulty", nil, "", "custom")
 	standardDifficulties := []string{"easy", "normal", "hard", "expert", "master", "append"}
 	difficultyImgVal := strings.ToLower(difficultyInput)
@@ -58,67 +202,35 @@ func GenerateAliasObject(levelID, distDir string, lastNoteTime float64, extraDat
 		difficultyImgVal = "custom"
 	}
 
-	vocalInput := getStringValue(extraData, "vocal", nil, "", "")
-	var vocalText string
-	if vocalInput != "" {
-		vocalText = fmt.Sprintf("Vo. %s", vocalInput)
-	} else {
-		vocalText = "Inst. ver."
-	}
-
-	// 置換マップを作成
-	replacements := map[string]string{
-		"{title}":          finalTitle,
-		"{author}":         finalAuthor,
-		"{words}":          getStringValue(extraData, "words", nil, "", "-"),
-		"{music}":          getStringValue(extraData, "music", nil, "", "-"),
-		"{arrange}":        getStringValue(extraData, "arrange", nil, "", "-"),
-		"{vocal}":          vocalText,
-		"{difficulty}":     strings.ToUpper(difficultyInput),
-		"{difficulty_img}": difficultyImgVal,
-	}
-
-	// 空白だった場合のデフォルト値を設定
-	for key, value := range replacements {
-		if value == "" {
-			if key == "{vocal}" {
-				replacements[key] = "Inst. ver."
-			} else {
-				replacements[key] = "-"
-			}
-		}
-	}
-
-	// パス情報
-	distFullPath := strings.ReplaceAll(filepath.ToSlash(distDir), "/", "\\")
-	assetsFullPath := strings.ReplaceAll(filepath.ToSlash(utils.ResourcePath("assets")), "/", "\\")
-	replacements["{distPath}"] = distFullPath
-	replacements["{assetsPath}"] = assetsFullPath
-
-	// フレーム計算
-	videoStartFrame := int(math.Round((lastNoteTime+1.0)*60)) + 316
-	fadeStartFrame := videoStartFrame + 161
-	fadeStopFrame := fadeStartFrame + 142
-	endFrame := fadeStopFrame + 124
-
-	replacements["{videoStartFrame}"] = fmt.Sprintf("%d", videoStartFrame)
-	replacements["{fadeStartFrame}"] = fmt.Sprintf("%d", fadeStartFrame)
-	replacements["{fadeStopFrame}"] = fmt.Sprintf("%d", fadeStopFrame)
-	replacements["{endFrame}"] = fmt.Sprintf("%d", endFrame)
+	rating, _ := itemData["rating"].(float64)
 
-	// 文字列を一括置換
-	outputContent := string(templateContent)
-	for placeholder, value := range replacements {
-		outputContent = strings.ReplaceAll(outputContent, placeholder, value)
+	// 入力されていないクレジットはlevel.jsonから補完する
+	// ボーカルに "-" が入力された場合はInst. ver.として扱う
+	credits := ExtractCredits(levelData)
+	vocal := getStringValue(extraData, "vocal", nil, "", credits.Vocal)
+	if vocal == "-" {
+		vocal = ""
 	}
 
-	// 結果を書き出し
-	if err := os.WriteFile(outputPath, []byte(outputContent), 0644); err != nil {
-		return "", fmt.Errorf("出力ファイルの書き込みに失敗しました: %w", err)
-	}
+	return SongInfo{
+		Title:         getStringValue(extraData, "title", itemData, "title", "-"),
+		Author:        getStringValue(extraData, "author", itemData, "author", "-"),
+		Words:         getStringValue(extraData, "words", nil, "", fallbackString(credits.Words, "-")),
+		Music:         getStringValue(extraData, "music", nil, "", fallbackString(credits.Music, "-")),
+		Arrange:       getStringValue(extraData, "arrange", nil, "", fallbackString(credits.Arrange, "-")),
+		Vocal:         vocal,
+		Difficulty:    difficultyInput,
+		DifficultyImg: difficultyImgVal,
+		Rating:        rating,
+	}, nil
+}
 
-	fmt.Printf("エイリアスオブジェクトを '%s' に保存しました。\n", outputPath)
-	return finalTitle, nil
+// fallbackString は値が空の場合に代替値を返す
+func fallbackString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
 }
 
 // getStringValue はマップから文字列値を取得するヘルパー関数
@@ -0,0 +1,561 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// AudioInfo は音源の長さと先頭・末尾の無音区間を表す構造体
+type AudioInfo struct {
+	Format          string
+	SampleRate      int
+	Duration        float64
+	LeadingSilence  float64
+	TrailingSilence float64
+}
+
+// silenceThreshold は無音とみなす振幅（約-60dBFS）
+const silenceThreshold = 0.001
+
+// LoadAudioInfo は音源ファイルを解析して長さと先頭・末尾の無音区間を取得する
+// 拡張子に関係なくファイルの先頭から形式（MP3・WAV・Ogg）を判定する
+func LoadAudioInfo(path string) (AudioInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("音源ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	var info AudioInfo
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		info, err = parseWAVInfo(data)
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		info, err = parseOggInfo(data)
+	default:
+		info, err = parseMP3Info(data)
+	}
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("音源ファイルの解析に失敗しました: %w", err)
+	}
+	return info, nil
+}
+
+// String は音源情報を表示用の文字列に変換する
+func (a AudioInfo) String() string {
+	return fmt.Sprintf("%s %dHz, 長さ %.3f秒, 先頭の無音 %.3f秒, 末尾の無音 %.3f秒",
+		a.Format, a.SampleRate, a.Duration, a.LeadingSilence, a.TrailingSilence)
+}
+
+// AudibleEnd は末尾の無音区間を除いた音が鳴り終わる位置（秒）を返す
+func (a AudioInfo) AudibleEnd() float64 {
+	return math.Max(a.Duration-a.TrailingSilence, 0)
+}
+
+// --- MP3 ---
+
+// mp3FrameHeader はMP3フレームヘッダーの内容を表す構造体
+type mp3FrameHeader struct {
+	mpeg1       bool
+	layer       int
+	protected   bool
+	sampleRate  int
+	channels    int
+	frameLength int
+	samples     int
+}
+
+var (
+	mp3BitratesV1 = [4][16]int{
+		3: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		2: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		1: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	}
+	mp3BitratesV2 = [4][16]int{
+		3: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		2: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		1: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = map[byte][3]int{
+		3: {44100, 48000, 32000}, // MPEG1
+		2: {22050, 24000, 16000}, // MPEG2
+		0: {11025, 12000, 8000},  // MPEG2.5
+	}
+)
+
+// parseMP3Header はフレームヘッダーを解析する（無効なヘッダーの場合はfalseを返す）
+func parseMP3Header(b []byte) (mp3FrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3FrameHeader{}, false
+	}
+
+	versionBits := (b[1] >> 3) & 0x03
+	layerBits := (b[1] >> 1) & 0x03
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 0x03
+	rates, ok := mp3SampleRates[versionBits]
+	if !ok || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mp3FrameHeader{}, false
+	}
+
+	h := mp3FrameHeader{
+		mpeg1:      versionBits == 3,
+		layer:      4 - int(layerBits),
+		protected:  b[1]&0x01 == 0,
+		sampleRate: rates[sampleRateIndex],
+		channels:   2,
+	}
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+
+	bitrate := mp3BitratesV2[layerBits][bitrateIndex] * 1000
+	if h.mpeg1 {
+		bitrate = mp3BitratesV1[layerBits][bitrateIndex] * 1000
+	}
+	padding := int((b[2] >> 1) & 0x01)
+
+	switch {
+	case h.layer == 1:
+		h.samples = 384
+		h.frameLength = (12*bitrate/h.sampleRate + padding) * 4
+	case h.layer == 3 && !h.mpeg1:
+		h.samples = 576
+		h.frameLength = 72*bitrate/h.sampleRate + padding
+	default:
+		h.samples = 1152
+		h.frameLength = 144*bitrate/h.sampleRate + padding
+	}
+	return h, h.frameLength > 4
+}
+
+// sideInfoSize はLayer IIIのサイド情報のバイト数を返す
+func (h mp3FrameHeader) sideInfoSize() int {
+	switch {
+	case h.mpeg1 && h.channels == 1:
+		return 17
+	case h.mpeg1:
+		return 32
+	case h.channels == 1:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// isSilent はLayer IIIのフレームが無音か（全グラニュールのメインデータが空か）を返す
+func (h mp3FrameHeader) isSilent(frame []byte) bool {
+	if h.layer != 3 {
+		return false
+	}
+	offset := 4
+	if h.protected {
+		offset += 2
+	}
+	if len(frame) < offset+h.sideInfoSize() {
+		return false
+	}
+
+	r := bitReader{data: frame[offset:]}
+	granules := 1
+	granuleBits := 63
+	if h.mpeg1 {
+		granules = 2
+		granuleBits = 59
+		r.skip(9)
+		if h.channels == 1 {
+			r.skip(5)
+		} else {
+			r.skip(3)
+		}
+		r.skip(4 * h.channels)
+	} else {
+		r.skip(8)
+		r.skip(h.channels)
+	}
+
+	for gr := 0; gr < granules; gr++ {
+		for ch := 0; ch < h.channels; ch++ {
+			if r.read(12) != 0 {
+				return false
+			}
+			r.skip(granuleBits - 12)
+		}
+	}
+	return true
+}
+
+// parseMP3Info はMP3のフレームを走査して長さと先頭・末尾の無音区間を求める
+// Xing/Info・VBRIヘッダーがある場合はその総フレーム数とエンコーダー遅延を優先する
+func parseMP3Info(data []byte) (AudioInfo, error) {
+	pos := skipID3v2(data)
+	pos = findMP3Sync(data, pos)
+	if pos < 0 {
+		return AudioInfo{}, fmt.Errorf("MP3フレームが見つかりません")
+	}
+
+	first, _ := parseMP3Header(data[pos:])
+	info := AudioInfo{Format: "MP3", SampleRate: first.sampleRate}
+
+	// 先頭フレームのXing/Info・VBRIヘッダー
+	tagFrames, delay, padding := 0, 0, 0
+	if frames, d, p, ok := parseXingHeader(data[pos:], first); ok {
+		tagFrames, delay, padding = frames, d, p
+		pos += first.frameLength
+	} else if frames, d, ok := parseVBRIHeader(data[pos:]); ok {
+		tagFrames, delay = frames, d
+		pos += first.frameLength
+	}
+
+	// フレームの走査
+	frameCount := 0
+	silentFrames := 0
+	trailingFrames := 0
+	leading := true
+	for pos+4 <= len(data) {
+		h, ok := parseMP3Header(data[pos:])
+		if !ok || h.sampleRate != first.sampleRate {
+			next := findMP3Sync(data, pos+1)
+			if next < 0 {
+				break
+			}
+			pos = next
+			continue
+		}
+
+		end := pos + h.frameLength
+		if end > len(data) {
+			end = len(data)
+		}
+		if h.isSilent(data[pos:end]) {
+			if leading {
+				silentFrames++
+			}
+			trailingFrames++
+		} else {
+			leading = false
+			trailingFrames = 0
+		}
+		frameCount++
+		pos += h.frameLength
+	}
+
+	if tagFrames > 0 {
+		frameCount = tagFrames
+	}
+	if frameCount == 0 {
+		return AudioInfo{}, fmt.Errorf("MP3フレームが見つかりません")
+	}
+
+	rate := float64(first.sampleRate)
+	totalSamples := frameCount*first.samples - delay - padding
+	info.Duration = math.Max(float64(totalSamples), 0) / rate
+	info.LeadingSilence = math.Max(float64(silentFrames*first.samples-delay), 0) / rate
+	if !leading {
+		info.TrailingSilence = math.Max(float64(trailingFrames*first.samples-padding), 0) / rate
+	}
+	return info, nil
+}
+
+// skipID3v2 はID3v2タグを読み飛ばした位置を返す
+func skipID3v2(data []byte) int {
+	if len(data) < 10 || string(data[0:3]) != "ID3" {
+		return 0
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10
+	}
+	if size > len(data) {
+		return len(data)
+	}
+	return size
+}
+
+// findMP3Sync は次のフレームも正しく続くフレームヘッダーの位置を探す
+func findMP3Sync(data []byte, pos int) int {
+	for ; pos+4 <= len(data); pos++ {
+		h, ok := parseMP3Header(data[pos:])
+		if !ok {
+			continue
+		}
+		next := pos + h.frameLength
+		if next+4 > len(data) {
+			return pos
+		}
+		if nh, ok := parseMP3Header(data[next:]); ok && nh.sampleRate == h.sampleRate {
+			return pos
+		}
+	}
+	return -1
+}
+
+// parseXingHeader はXing/Infoヘッダーから総フレーム数と、LAMEタグのエンコーダー遅延・パディングを取得する
+func parseXingHeader(frame []byte, h mp3FrameHeader) (int, int, int, bool) {
+	offset := 4 + h.sideInfoSize()
+	if h.protected {
+		offset += 2
+	}
+	if len(frame) < offset+8 {
+		return 0, 0, 0, false
+	}
+	id := string(frame[offset : offset+4])
+	if id != "Xing" && id != "Info" {
+		return 0, 0, 0, false
+	}
+
+	flags := binary.BigEndian.Uint32(frame[offset+4:])
+	pos := offset + 8
+	frames := 0
+	if flags&0x01 != 0 && len(frame) >= pos+4 {
+		frames = int(binary.BigEndian.Uint32(frame[pos:]))
+		pos += 4
+	}
+	if flags&0x02 != 0 {
+		pos += 4
+	}
+	if flags&0x04 != 0 {
+		pos += 100
+	}
+	if flags&0x08 != 0 {
+		pos += 4
+	}
+
+	delay, padding := 0, 0
+	if len(frame) >= pos+24 {
+		encoder := frame[pos : pos+4]
+		if bytes.Equal(encoder, []byte("LAME")) || bytes.Equal(encoder, []byte("Lavc")) || bytes.Equal(encoder, []byte("Lavf")) {
+			v := frame[pos+21:]
+			delay = int(v[0])<<4 | int(v[1])>>4
+			padding = int(v[1]&0x0F)<<8 | int(v[2])
+		}
+	}
+	return frames, delay, padding, frames > 0
+}
+
+// parseVBRIHeader はVBRIヘッダーから総フレーム数とエンコーダー遅延を取得する
+func parseVBRIHeader(frame []byte) (int, int, bool) {
+	const offset = 4 + 32
+	if len(frame) < offset+18 || string(frame[offset:offset+4]) != "VBRI" {
+		return 0, 0, false
+	}
+	delay := int(binary.BigEndian.Uint16(frame[offset+6:]))
+	frames := int(binary.BigEndian.Uint32(frame[offset+14:]))
+	return frames, delay, frames > 0
+}
+
+// bitReader はビット単位でデータを読み取る
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		byteIndex := r.pos >> 3
+		bit := 0
+		if byteIndex < len(r.data) {
+			bit = int(r.data[byteIndex]>>(7-uint(r.pos&7))) & 1
+		}
+		value = value<<1 | bit
+		r.pos++
+	}
+	return value
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
+
+// --- WAV ---
+
+// parseWAVInfo はWAVのfmt・dataチャンクから長さを求め、PCMデータから先頭・末尾の無音区間を求める
+func parseWAVInfo(data []byte) (AudioInfo, error) {
+	var (
+		audioFormat   uint16
+		channels      int
+		sampleRate    int
+		blockAlign    int
+		bitsPerSample int
+		samples       []byte
+		hasFormat     bool
+	)
+
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8:]
+		if size > len(body) || size < 0 {
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return AudioInfo{}, fmt.Errorf("WAVのfmtチャンクが不正です")
+			}
+			audioFormat = binary.LittleEndian.Uint16(body[0:])
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:]))
+			blockAlign = int(binary.LittleEndian.Uint16(body[12:]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(body[14:]))
+			if audioFormat == 0xFFFE && size >= 26 {
+				audioFormat = binary.LittleEndian.Uint16(body[24:])
+			}
+			hasFormat = true
+		case "data":
+			samples = body
+		}
+		pos += 8 + size + size%2
+	}
+
+	if !hasFormat || samples == nil || sampleRate == 0 || blockAlign == 0 || channels == 0 {
+		return AudioInfo{}, fmt.Errorf("WAVのfmt・dataチャンクが見つかりません")
+	}
+
+	info := AudioInfo{
+		Format:     "WAV",
+		SampleRate: sampleRate,
+		Duration:   float64(len(samples)/blockAlign) / float64(sampleRate),
+	}
+
+	// 先頭から閾値を超えるサンプルを探す
+	bytesPerSample := bitsPerSample / 8
+	if bytesPerSample == 0 || bytesPerSample*channels > blockAlign {
+		return info, nil
+	}
+	frames := len(samples) / blockAlign
+	audible := func(i int) (bool, bool) {
+		frame := samples[i*blockAlign:]
+		for ch := 0; ch < channels; ch++ {
+			amplitude, ok := wavSampleAmplitude(frame[ch*bytesPerSample:], audioFormat, bitsPerSample)
+			if !ok {
+				return false, false
+			}
+			if amplitude > silenceThreshold {
+				return true, true
+			}
+		}
+		return false, true
+	}
+
+	first := -1
+	for i := 0; i < frames; i++ {
+		found, ok := audible(i)
+		if !ok {
+			return info, nil
+		}
+		if found {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		info.LeadingSilence = info.Duration
+		return info, nil
+	}
+	info.LeadingSilence = float64(first) / float64(sampleRate)
+
+	// 末尾から閾値を超えるサンプルを探す
+	for i := frames - 1; i >= first; i-- {
+		if found, _ := audible(i); found {
+			info.TrailingSilence = float64(frames-1-i) / float64(sampleRate)
+			break
+		}
+	}
+	return info, nil
+}
+
+// wavSampleAmplitude はサンプルの振幅を0〜1の範囲で返す（未対応の形式の場合はfalseを返す）
+func wavSampleAmplitude(b []byte, audioFormat uint16, bits int) (float64, bool) {
+	switch {
+	case audioFormat == 1 && bits == 8:
+		return math.Abs(float64(int(b[0])-128) / 128), true
+	case audioFormat == 1 && bits == 16:
+		return math.Abs(float64(int16(binary.LittleEndian.Uint16(b))) / 32768), true
+	case audioFormat == 1 && bits == 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return math.Abs(float64(v) / 8388608), true
+	case audioFormat == 1 && bits == 32:
+		return math.Abs(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648), true
+	case audioFormat == 3 && bits == 32:
+		return math.Abs(float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))), true
+	case audioFormat == 3 && bits == 64:
+		return math.Abs(math.Float64frombits(binary.LittleEndian.Uint64(b))), true
+	}
+	return 0, false
+}
+
+// --- Ogg ---
+
+// parseOggInfo はOggページのグラニュール位置から長さを求める（Vorbis・Opus）
+// 先頭・末尾の無音区間はデコードしないと分からないため0とする
+func parseOggInfo(data []byte) (AudioInfo, error) {
+	var (
+		info        AudioInfo
+		serial      uint32
+		preSkip     int64
+		lastGranule int64 = -1
+		identified  bool
+	)
+
+	pos := 0
+	for pos+27 <= len(data) {
+		if string(data[pos:pos+4]) != "OggS" {
+			next := bytes.Index(data[pos+1:], []byte("OggS"))
+			if next < 0 {
+				break
+			}
+			pos += next + 1
+			continue
+		}
+
+		granule := int64(binary.LittleEndian.Uint64(data[pos+6:]))
+		pageSerial := binary.LittleEndian.Uint32(data[pos+14:])
+		segments := int(data[pos+26])
+		if pos+27+segments > len(data) {
+			break
+		}
+		bodySize := 0
+		for _, size := range data[pos+27 : pos+27+segments] {
+			bodySize += int(size)
+		}
+		bodyStart := pos + 27 + segments
+		bodyEnd := bodyStart + bodySize
+		if bodyEnd > len(data) {
+			bodyEnd = len(data)
+		}
+		body := data[bodyStart:bodyEnd]
+
+		if !identified {
+			switch {
+			case len(body) >= 16 && body[0] == 1 && string(body[1:7]) == "vorbis":
+				info.Format = "Ogg Vorbis"
+				info.SampleRate = int(binary.LittleEndian.Uint32(body[12:]))
+			case len(body) >= 16 && string(body[0:8]) == "OpusHead":
+				info.Format = "Ogg Opus"
+				info.SampleRate = 48000
+				preSkip = int64(binary.LittleEndian.Uint16(body[10:]))
+			default:
+				return AudioInfo{}, fmt.Errorf("対応していないOggストリームです")
+			}
+			serial = pageSerial
+			identified = true
+		} else if pageSerial == serial && granule >= 0 {
+			lastGranule = granule
+		}
+		pos = bodyStart + bodySize
+	}
+
+	if !identified || info.SampleRate == 0 || lastGranule < 0 {
+		return AudioInfo{}, fmt.Errorf("Oggストリームの長さを取得できません")
+	}
+	info.Duration = math.Max(float64(lastGranule-preSkip), 0) / float64(info.SampleRate)
+	return info, nil
+}
@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/audioの音源は、パーサーが読む部分だけを持つ小さなファイル
+//   - silence_tone.wav: 16bit モノラル 4000Hz、無音0.5秒・矩形波1秒・無音0.25秒
+//   - lame_cbr.mp3: ID3v2タグ付き、MPEG1 Layer III 44100Hz モノラル 32kbps
+//     Infoヘッダー（10フレーム、LAMEタグの遅延576・パディング288）のあとに無音3・有音5・無音2フレーム
+//   - lame_cbr_crc.mp3: lame_cbr.mp3と同じ内容をCRC付きのフレームにしたもの（ID3v2タグなし）
+//   - vorbis.ogg: 44100Hz、最後のグラニュール位置88200
+//   - opus.ogg: プリスキップ312、最後のグラニュール位置72312
+func TestLoadAudioInfo(t *testing.T) {
+	const mp3Rate = 44100.0
+	mp3 := AudioInfo{
+		Format:          "MP3",
+		SampleRate:      44100,
+		Duration:        (10*1152 - 576 - 288) / mp3Rate,
+		LeadingSilence:  (3*1152 - 576) / mp3Rate,
+		TrailingSilence: (2*1152 - 288) / mp3Rate,
+	}
+
+	for _, tc := range []struct {
+		file string
+		want AudioInfo
+	}{
+		{"silence_tone.wav", AudioInfo{Format: "WAV", SampleRate: 4000, Duration: 1.75, LeadingSilence: 0.5, TrailingSilence: 0.25}},
+		{"lame_cbr.mp3", mp3},
+		{"lame_cbr_crc.mp3", mp3},
+		{"vorbis.ogg", AudioInfo{Format: "Ogg Vorbis", SampleRate: 44100, Duration: 2}},
+		{"opus.ogg", AudioInfo{Format: "Ogg Opus", SampleRate: 48000, Duration: 1.5}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			got, err := LoadAudioInfo(filepath.Join("testdata", "audio", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format != tc.want.Format || got.SampleRate != tc.want.SampleRate ||
+				!approxEqual(got.Duration, tc.want.Duration) ||
+				!approxEqual(got.LeadingSilence, tc.want.LeadingSilence) ||
+				!approxEqual(got.TrailingSilence, tc.want.TrailingSilence) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAudioInfoInvalid(t *testing.T) {
+	if _, err := LoadAudioInfo(filepath.Join("testdata", "project_1080p60.aup2")); err == nil {
+		t.Error("音源でないファイルでエラーになりませんでした")
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,293 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sekai-overlay-go/internal/utils"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultBackgroundLayout は標準で使用する背景レイアウト名
+const DefaultBackgroundLayout = "v3"
+
+// backgroundLayoutFile は背景レイアウトを定義するファイル名
+const backgroundLayoutFile = "layout.json"
+
+// BackgroundLayout は背景画像の合成手順を表す構造体
+// assets/background/<名前>/layout.json から読み込む
+type BackgroundLayout struct {
+	Name string `json:"-"`
+	// Base は一番下に置く画像で、出力サイズもこの画像に合わせる
+	Base   string            `json:"base"`
+	Layers []BackgroundLayer `json:"layers"`
+
+	dir string
+}
+
+// BackgroundLayer は背景レイアウトの1レイヤー
+// image・jacket・layersのいずれか1つを指定し、リストの順に上へ重ねる
+type BackgroundLayer struct {
+	// Image はレイアウトと同じフォルダにある画像
+	Image string `json:"image,omitempty"`
+	// Jacket はジャケットを射影変換する四隅の座標（左上・右上・左下・右下の順）
+	Jacket [][2]int `json:"jacket,omitempty"`
+	// Layers は透明なキャンバスに重ねてから1枚として扱うレイヤー
+	Layers []BackgroundLayer `json:"layers,omitempty"`
+	// Mask はアルファに掛けるマスク画像
+	Mask string `json:"mask,omitempty"`
+	// Opacity は重ねるときの不透明度（0〜1、省略時は1）
+	Opacity *float64 `json:"opacity,omitempty"`
+	// Tint はレイヤーに付ける色（dominant/accent/shade はジャケットから抽出した色、または#rrggbb）
+	Tint string `json:"tint,omitempty"`
+	// TintAmount は色を付ける強さ（0〜1、省略時は1）
+	TintAmount *float64 `json:"tint_amount,omitempty"`
+}
+
+// ListBackgroundLayouts はassets/background内の背景レイアウト名の一覧を取得する
+func ListBackgroundLayouts() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(utils.ResourcePath("assets/background"), "*", backgroundLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("背景レイアウトの検索に失敗しました: %w", err)
+	}
+
+	names := make([]string, 0, len(matches))
+	for _, path := range matches {
+		names = append(names, filepath.Base(filepath.Dir(path)))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ResolveBackgroundLayout はバージョン名を背景レイアウト名に変換する
+// 以前の設定との互換性のため、"3"のような数字だけの指定は"v3"として扱う
+func ResolveBackgroundLayout(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		version = DefaultBackgroundLayout
+	}
+
+	names, err := ListBackgroundLayouts()
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{version, "v" + version} {
+		for _, name := range names {
+			if strings.EqualFold(name, candidate) {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("背景レイアウト '%s' が見つかりません (%s)", version, strings.Join(names, "/"))
+}
+
+// LoadBackgroundLayout は背景レイアウトを読み込む
+func LoadBackgroundLayout(version string) (*BackgroundLayout, error) {
+	name, err := ResolveBackgroundLayout(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBackgroundLayout(name, utils.ResourcePath(filepath.Join("assets", "background", name)))
+}
+
+// parseBackgroundLayout はdirのlayout.jsonを解析し、参照している画像が揃っているか確認する
+func parseBackgroundLayout(name, dir string) (*BackgroundLayout, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backgroundLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("背景レイアウトの読み込みに失敗しました: %w", err)
+	}
+
+	layout := &BackgroundLayout{Name: name, dir: dir}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// 未対応のレイヤーの種類（キーの書き間違いを含む）を無視しないようにする
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(layout); err != nil {
+		return nil, fmt.Errorf("背景レイアウト '%s' の解析に失敗しました: %w", name, err)
+	}
+	if layout.Base == "" {
+		return nil, fmt.Errorf("背景レイアウト '%s' にbaseが指定されていません", name)
+	}
+	if err := layout.checkAsset("base", layout.Base); err != nil {
+		return nil, fmt.Errorf("背景レイアウト '%s' が不正です: %w", name, err)
+	}
+	if err := layout.validateLayers(layout.Layers, "layers"); err != nil {
+		return nil, fmt.Errorf("背景レイアウト '%s' が不正です: %w", name, err)
+	}
+	return layout, nil
+}
+
+// checkAsset はレイアウトのフォルダに画像があるか確認する
+func (l *BackgroundLayout) checkAsset(where, file string) error {
+	if _, err := os.Stat(filepath.Join(l.dir, file)); err != nil {
+		return fmt.Errorf("%s: 画像 '%s' が見つかりません", where, file)
+	}
+	return nil
+}
+
+// validateLayers はレイヤーの指定が正しいか確認する
+func (l *BackgroundLayout) validateLayers(layers []BackgroundLayer, path string) error {
+	for i, layer := range layers {
+		where := fmt.Sprintf("%s[%d]", path, i)
+
+		kinds := 0
+		if layer.Image != "" {
+			kinds++
+			if err := l.checkAsset(where, layer.Image); err != nil {
+				return err
+			}
+		}
+		if layer.Jacket != nil {
+			kinds++
+			if len(layer.Jacket) != 4 {
+				return fmt.Errorf("%s: jacketには四隅の座標を4つ指定してください", where)
+			}
+		}
+		if layer.Layers != nil {
+			kinds++
+			if err := l.validateLayers(layer.Layers, where+".layers"); err != nil {
+				return err
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("%s: image・jacket・layersのいずれか1つを指定してください", where)
+		}
+		if layer.Mask != "" {
+			if err := l.checkAsset(where+".mask", layer.Mask); err != nil {
+				return err
+			}
+		}
+
+		if layer.Opacity != nil && (*layer.Opacity < 0 || *layer.Opacity > 1) {
+			return fmt.Errorf("%s: opacityは0〜1で指定してください", where)
+		}
+		if layer.Tint != "" {
+			if _, err := (Palette{}).Resolve(layer.Tint); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+		}
+		if layer.TintAmount != nil && (*layer.TintAmount < 0 || *layer.TintAmount > 1) {
+			return fmt.Errorf("%s: tint_amountは0〜1で指定してください", where)
+		}
+	}
+	return nil
+}
+
+// Render はレイアウトに従ってジャケットから背景画像を合成する
+func (l *BackgroundLayout) Render(jacket image.Image, filter ResampleFilter) (*image.NRGBA, error) {
+	// 各モーフィングで変換し直さないように、ジャケットを先にNRGBAにしておく
+	r := &backgroundRenderer{
+		layout: l,
+		jacket: imaging.Clone(jacket),
+		filter: filter,
+		assets: map[string]image.Image{},
+	}
+
+	base, err := r.asset(l.Base)
+	if err != nil {
+		return nil, err
+	}
+	r.size = image.Rect(0, 0, base.Bounds().Dx(), base.Bounds().Dy())
+
+	return r.composite(imaging.Clone(base), l.Layers)
+}
+
+// backgroundRenderer は背景レイアウトの合成中の状態を保持する
+type backgroundRenderer struct {
+	layout *BackgroundLayout
+	jacket *image.NRGBA
+	filter ResampleFilter
+	size   image.Rectangle
+	// 同じ画像を何度も読み込まないようにキャッシュする
+	assets map[string]image.Image
+	// tintを使うレイヤーがあるときだけジャケットから抽出する
+	palette *Palette
+}
+
+// asset はレイアウトのフォルダにある画像を読み込む
+func (r *backgroundRenderer) asset(name string) (image.Image, error) {
+	if img, ok := r.assets[name]; ok {
+		return img, nil
+	}
+	img, err := imaging.Open(filepath.Join(r.layout.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("背景素材 '%s' の読み込みに失敗しました: %w", name, err)
+	}
+	r.assets[name] = img
+	return img, nil
+}
+
+// composite はdstにレイヤーを順に重ねる
+func (r *backgroundRenderer) composite(dst *image.NRGBA, layers []BackgroundLayer) (*image.NRGBA, error) {
+	for _, layer := range layers {
+		img, err := r.render(layer)
+		if err != nil {
+			return nil, err
+		}
+
+		opacity := 1.0
+		if layer.Opacity != nil {
+			opacity = *layer.Opacity
+		}
+		dst = imaging.Overlay(dst, img, image.Point{}, opacity)
+	}
+	return dst, nil
+}
+
+// render は1レイヤー分の画像を生成し、色付けとマスクがあれば適用する
+func (r *backgroundRenderer) render(layer BackgroundLayer) (image.Image, error) {
+	var img image.Image
+	switch {
+	case layer.Image != "":
+		asset, err := r.asset(layer.Image)
+		if err != nil {
+			return nil, err
+		}
+		img = asset
+	case layer.Jacket != nil:
+		quad := make([]image.Point, len(layer.Jacket))
+		for i, p := range layer.Jacket {
+			quad[i] = image.Point{X: p[0], Y: p[1]}
+		}
+		img = morphImage(r.jacket, quad, r.size, r.filter)
+	default:
+		canvas := imaging.New(r.size.Dx(), r.size.Dy(), image.Transparent)
+		group, err := r.composite(canvas, layer.Layers)
+		if err != nil {
+			return nil, err
+		}
+		img = group
+	}
+
+	if layer.Tint != "" {
+		if r.palette == nil {
+			palette := ExtractPalette(r.jacket)
+			r.palette = &palette
+		}
+		tint, err := r.palette.Resolve(layer.Tint)
+		if err != nil {
+			return nil, err
+		}
+		amount := 1.0
+		if layer.TintAmount != nil {
+			amount = *layer.TintAmount
+		}
+		img = tintImage(img, tint, amount)
+	}
+
+	if layer.Mask != "" {
+		mask, err := r.asset(layer.Mask)
+		if err != nil {
+			return nil, err
+		}
+		img = applyMask(img, mask)
+	}
+	return img, nil
+}
@@ -0,0 +1,150 @@
+package modules
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// testJacket は色の変化がわかるグラデーションと市松模様のジャケット
+func testJacket() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 740, 740))
+	for y := 0; y < 740; y++ {
+		for x := 0; x < 740; x++ {
+			b := uint8(64)
+			if (x/185+y/185)%2 == 0 {
+				b = 192
+			}
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 255 / 739), uint8(y * 255 / 739), b, 255})
+		}
+	}
+	return img
+}
+
+// TestBackgroundLayoutGolden は同梱のレイアウトが以前のバージョン（renderV1/renderV3）と同じ背景を合成するかを確認する
+// ゴールデン画像は1/4に縮小して保存しているので、同じように縮小して比較する
+// ジャケットの辺のアンチエイリアスと補間の丸めの違いがあるため、わずかな差は許容する
+func TestBackgroundLayoutGolden(t *testing.T) {
+	useRepoAssets(t)
+	for _, version := range []string{"v1", "v3"} {
+		t.Run(version, func(t *testing.T) {
+			layout, err := LoadBackgroundLayout(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := layout.Render(testJacket(), ResampleBilinear)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := imaging.Resize(img, img.Rect.Dx()/4, img.Rect.Dy()/4, imaging.Box)
+			want := loadGolden(t, "background_"+version+".png")
+			if got.Rect != want.Rect {
+				t.Fatalf("size = %v, want %v", got.Rect.Size(), want.Rect.Size())
+			}
+
+			quads := jacketQuads(layout.Layers)
+			checked := 0
+			for y := 0; y < want.Rect.Dy(); y++ {
+				for x := 0; x < want.Rect.Dx(); x++ {
+					// 縮小前の座標でジャケットの辺から4ピクセル以内は比較しない
+					if nearQuadEdge(quads, float64(x*4+2), float64(y*4+2), 4) {
+						continue
+					}
+					checked++
+					g, w := got.NRGBAAt(x, y), want.NRGBAAt(x, y)
+					for _, d := range []int{int(g.R) - int(w.R), int(g.G) - int(w.G), int(g.B) - int(w.B), int(g.A) - int(w.A)} {
+						if d < -3 || d > 3 {
+							t.Fatalf("(%d, %d): got %v, want %v", x*4, y*4, g, w)
+						}
+					}
+				}
+			}
+			if checked < len(want.Pix)/4*9/10 {
+				t.Errorf("比較した画素が少なすぎます: %d", checked)
+			}
+		})
+	}
+}
+
+// jacketQuads はレイヤーに含まれるジャケットの四角形を集める
+func jacketQuads(layers []BackgroundLayer) [][][2]int {
+	var quads [][][2]int
+	for _, layer := range layers {
+		if layer.Jacket != nil {
+			quads = append(quads, layer.Jacket)
+		}
+		quads = append(quads, jacketQuads(layer.Layers)...)
+	}
+	return quads
+}
+
+// nearQuadEdge は点がいずれかの四角形（左上・右上・左下・右下の順）の辺から距離d以内にあるかを返す
+func nearQuadEdge(quads [][][2]int, x, y, d float64) bool {
+	for _, q := range quads {
+		// 左上→右上→右下→左下の順に辺をたどる
+		corners := [][2]int{q[0], q[1], q[3], q[2]}
+		for i := range corners {
+			a, b := corners[i], corners[(i+1)%4]
+			if segmentDistance(x, y, float64(a[0]), float64(a[1]), float64(b[0]), float64(b[1])) <= d {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentDistance は点(px, py)と線分(ax, ay)-(bx, by)の距離を返す
+func segmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = clampFloat(((px-ax)*dx+(py-ay)*dy)/length, 0, 1)
+	}
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+func TestParseBackgroundLayoutErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		layout string
+		want   string
+	}{
+		{"正常", `{"base": "base.png", "layers": [{"mask": "mask.png", "layers": [{"jacket": [[0, 0], [9, 0], [0, 9], [9, 9]]}]}, {"image": "cover.png"}]}`, ""},
+		{"未対応のレイヤー", `{"base": "base.png", "layers": [{"video": "cover.mp4"}]}`, "video"},
+		{"キーの書き間違い", `{"base": "base.png", "layers": [{"image": "cover.png", "opacty": 0.5}]}`, "opacty"},
+		{"種類の指定なし", `{"base": "base.png", "layers": [{"opacity": 0.5}]}`, "いずれか1つ"},
+		{"マスクがない", `{"base": "base.png", "layers": [{"mask": "missing.png", "layers": [{"image": "cover.png"}]}]}`, "missing.png"},
+		{"画像がない", `{"base": "base.png", "layers": [{"layers": [{"image": "missing.png"}]}]}`, "layers[0].layers[0]"},
+		{"baseがない", `{"base": "missing.png", "layers": []}`, "missing.png"},
+		{"四隅が足りない", `{"base": "base.png", "layers": [{"jacket": [[0, 0], [9, 0], [0, 9]]}]}`, "四隅"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range []string{"base.png", "mask.png", "cover.png"} {
+				if err := imaging.Save(image.NewNRGBA(image.Rect(0, 0, 10, 10)), filepath.Join(dir, name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, backgroundLayoutFile), []byte(tc.layout), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := parseBackgroundLayout("test", dir)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
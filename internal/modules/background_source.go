@@ -0,0 +1,146 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sekai-overlay-go/internal/config"
+
+	"github.com/disintegration/imaging"
+)
+
+// BackgroundSource は背景画像の取得方法
+type BackgroundSource string
+
+// 背景画像の取得方法の種類
+const (
+	// BackgroundGenerated はジャケットから背景レイアウトで合成する
+	BackgroundGenerated BackgroundSource = "generated"
+	// BackgroundServer は譜面に設定されたSonolusの背景の画像を使う
+	BackgroundServer BackgroundSource = "server"
+	// BackgroundFile はローカルの画像ファイルを使う
+	BackgroundFile BackgroundSource = "file"
+)
+
+// BackgroundSourceNames は選択できる背景画像の取得方法の一覧
+var BackgroundSourceNames = []string{string(BackgroundGenerated), string(BackgroundServer), string(BackgroundFile)}
+
+// BackgroundZoom は背景画像を表示する拡大率（1080p基準、0の場合はテンプレートのレイアウトの値）を返す
+// 用意した画像の背景は出力解像度で保存するので、テンプレートの拡大率を使わず等倍で表示する
+func BackgroundZoom(source BackgroundSource, format VideoFormat) float64 {
+	if source == BackgroundGenerated {
+		return 0
+	}
+	return 100 / format.Scale()
+}
+
+// serverBackgroundFile はダウンロードしたSonolusの背景画像の保存名（拡張子は付けない）
+const serverBackgroundFile = "background_source"
+
+// ParseBackgroundSource は背景画像の取得方法の名前を解析する（空の場合は生成）
+func ParseBackgroundSource(name string) (BackgroundSource, error) {
+	switch source := BackgroundSource(strings.ToLower(strings.TrimSpace(name))); source {
+	case "":
+		return BackgroundGenerated, nil
+	case BackgroundGenerated, BackgroundServer, BackgroundFile:
+		return source, nil
+	}
+	return "", fmt.Errorf("背景の種類 '%s' はサポートされていません (%s)", name, strings.Join(BackgroundSourceNames, "/"))
+}
+
+// DownloadLevelBackground はlevel.jsonのuseBackgroundから背景の画像をダウンロードし、保存先のパスを返す
+// 譜面がエンジンの標準の背景を使う場合はエラーを返す
+func DownloadLevelBackground(prefix, distDir string) (string, error) {
+	levelFile, err := os.Open(filepath.Join(distDir, "level.json"))
+	if err != nil {
+		return "", fmt.Errorf("level.jsonの読み込みに失敗しました: %w", err)
+	}
+	defer levelFile.Close()
+
+	var levelData map[string]interface{}
+	if err := json.NewDecoder(levelFile).Decode(&levelData); err != nil {
+		return "", fmt.Errorf("level.jsonの解析に失敗しました: %w", err)
+	}
+
+	item, _ := levelData["item"].(map[string]interface{})
+	useBackground, _ := item["useBackground"].(map[string]interface{})
+	if useDefault, _ := useBackground["useDefault"].(bool); useDefault {
+		return "", fmt.Errorf("この譜面には背景が設定されていません")
+	}
+	background, _ := useBackground["item"].(map[string]interface{})
+	backgroundImage, _ := background["image"].(map[string]interface{})
+	imageURL, _ := backgroundImage["url"].(string)
+	if imageURL == "" {
+		return "", fmt.Errorf("背景の画像URLが見つかりません")
+	}
+
+	// URLはサーバーからの相対パスの場合がある
+	if base, err := url.Parse(config.ServerMap[prefix]); err == nil {
+		if ref, err := url.Parse(imageURL); err == nil {
+			imageURL = base.ResolveReference(ref).String()
+		}
+	}
+
+	if title, ok := background["title"].(string); ok {
+		fmt.Printf("譜面の背景 '%s' をダウンロードしています...\n", title)
+	}
+	outputPath := filepath.Join(distDir, serverBackgroundFile)
+	if err := downloadFile(imageURL, outputPath); err != nil {
+		return "", fmt.Errorf("背景画像のダウンロードに失敗しました: %w", err)
+	}
+	return outputPath, nil
+}
+
+// backgroundOutput は出力する背景画像のファイル名とサイズ
+type backgroundOutput struct {
+	name          string
+	width, height int
+}
+
+// GenerateBackgroundFromImage は用意された画像を出力解像度に合わせて拡大・切り抜きしてbackground.pngを出力する
+// 縦向きの場合はbackground_portrait.pngも元の画像から直接切り抜く
+// 派生画像は横向きの背景画像とジャケットから作成する
+func GenerateBackgroundFromImage(imagePath, distDir string, format VideoFormat, variants BackgroundVariants) error {
+	fmt.Println("背景画像の生成を開始します...")
+
+	source, err := imaging.Open(imagePath)
+	if err != nil {
+		return fmt.Errorf("背景画像の読み込みに失敗しました: %w", err)
+	}
+
+	// 画面に表示する背景は出力解像度と同じサイズにする（縦向きの横向き背景は派生画像用）
+	outputs := []backgroundOutput{{"background.png", format.Width, format.Height}}
+	if format.IsPortrait() {
+		outputs = []backgroundOutput{
+			{"background.png", format.Height, format.Width},
+			{"background_portrait.png", format.Width, format.Height},
+		}
+	}
+
+	var landscape image.Image
+	for _, output := range outputs {
+		fitted := imaging.Fill(source, output.width, output.height, imaging.Center, imaging.Lanczos)
+		outputPath := filepath.Join(distDir, output.name)
+		if err := imaging.Save(fitted, outputPath); err != nil {
+			return fmt.Errorf("背景画像の保存に失敗しました: %w", err)
+		}
+		fmt.Printf("背景画像を '%s' に保存しました。\n", outputPath)
+		if landscape == nil {
+			landscape = fitted
+		}
+	}
+
+	if !variants.Enabled {
+		return nil
+	}
+	jacket, err := imaging.Open(jacketImagePath(distDir))
+	if err != nil {
+		return fmt.Errorf("ジャケット画像の読み込みに失敗しました: %w", err)
+	}
+	return writeBackgroundVariants(landscape, jacket, distDir, variants)
+}
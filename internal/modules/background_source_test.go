@@ -0,0 +1,100 @@
+package modules
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestGenerateBackgroundFromImage(t *testing.T) {
+	for _, tc := range []struct {
+		resolution string
+		layout     string
+		want       map[string]image.Point
+	}{
+		{"1080p", LayoutLandscape, map[string]image.Point{"background.png": {1920, 1080}}},
+		{"4k", LayoutLandscape, map[string]image.Point{"background.png": {3840, 2160}}},
+		{"1080p", LayoutPortrait, map[string]image.Point{"background.png": {1920, 1080}, "background_portrait.png": {1080, 1920}}},
+	} {
+		t.Run(tc.resolution+"_"+tc.layout, func(t *testing.T) {
+			format, err := NewVideoFormat(60, tc.resolution, tc.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			distDir := t.TempDir()
+			// 横長の画像は左右が切り抜かれる
+			source := imaging.New(1000, 400, color.NRGBA{200, 100, 50, 255})
+			sourcePath := filepath.Join(distDir, "source.png")
+			if err := imaging.Save(source, sourcePath); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := GenerateBackgroundFromImage(sourcePath, distDir, format, BackgroundVariants{}); err != nil {
+				t.Fatal(err)
+			}
+			for name, size := range tc.want {
+				img, err := imaging.Open(filepath.Join(distDir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := img.Bounds().Size(); got != size {
+					t.Errorf("%s: size = %v, want %v", name, got, size)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateBackgroundZoom(t *testing.T) {
+	tmpl := AliasTemplate{Name: DefaultAliasTemplate, Path: filepath.Join("..", "..", "assets", "alias", "template.object")}
+
+	for _, tc := range []struct {
+		resolution string
+		layout     string
+		source     BackgroundSource
+		want       string
+	}{
+		{"1080p", LayoutLandscape, BackgroundGenerated, "120.000"},
+		{"4k", LayoutLandscape, BackgroundGenerated, "240.000"},
+		{"1080p", LayoutLandscape, BackgroundFile, "100.000"},
+		{"4k", LayoutLandscape, BackgroundServer, "100.000"},
+		{"1440p", LayoutPortrait, BackgroundFile, "100.000"},
+	} {
+		format, err := NewVideoFormat(60, tc.resolution, tc.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := testAliasData(format)
+		data["backgroundZoom"] = BackgroundZoom(tc.source, format)
+		rendered, err := executeAliasTemplate(tmpl, data, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objectFile, err := ParseObjectFile(rendered)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := 0
+		for _, obj := range objectFile.Objects {
+			effect := obj.Effect("画像ファイル")
+			if effect == nil {
+				continue
+			}
+			if file, _ := effect.Get("ファイル"); !strings.Contains(file, `\background`) {
+				continue
+			}
+			found++
+			if zoom, _ := obj.Effect("標準描画").Get("拡大率"); zoom != tc.want {
+				t.Errorf("%s %s %s: 拡大率 = %s, want %s", tc.resolution, tc.layout, tc.source, zoom, tc.want)
+			}
+		}
+		if found == 0 {
+			t.Errorf("%s %s: 背景のオブジェクトが見つかりません", tc.resolution, tc.layout)
+		}
+	}
+}
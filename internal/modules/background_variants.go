@@ -0,0 +1,109 @@
+package modules
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+)
+
+// 派生画像のデフォルト値
+const (
+	DefaultBlurRadius    = 24.0
+	DefaultDimBrightness = 0.5
+)
+
+const (
+	// jacketCardBorder はジャケットカードの白い縁の幅
+	jacketCardBorder = 16
+	// jacketCardShadow はジャケットカードの影の広がり
+	jacketCardShadow = 24
+)
+
+// BackgroundVariants は背景画像から派生画像を作るときの設定
+type BackgroundVariants struct {
+	// Enabled がfalseの場合は派生画像を出力しない
+	Enabled bool
+	// BlurRadius はぼかしの強さ（ガウスぼかしの標準偏差、px）
+	BlurRadius float64
+	// Brightness はbackground_dim.pngの明るさ（0〜1）
+	Brightness float64
+}
+
+// withDefaults は未指定の値をデフォルト値で補う
+func (v BackgroundVariants) withDefaults() BackgroundVariants {
+	if v.BlurRadius <= 0 {
+		v.BlurRadius = DefaultBlurRadius
+	}
+	if v.Brightness <= 0 || v.Brightness > 1 {
+		v.Brightness = DefaultDimBrightness
+	}
+	return v
+}
+
+// writeBackgroundVariants は合成した背景画像とジャケットから派生画像を出力する
+// background_blur.pngはぼかした背景、background_dim.pngはそれを暗くした背景、jacket_card.pngは白い縁と影を付けたジャケット
+func writeBackgroundVariants(background, jacket image.Image, distDir string, variants BackgroundVariants) error {
+	if !variants.Enabled {
+		return nil
+	}
+	variants = variants.withDefaults()
+
+	blurred := blurImage(background, variants.BlurRadius)
+	dimmed := imaging.AdjustFunc(blurred, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{
+			R: clampUint8(float64(c.R) * variants.Brightness),
+			G: clampUint8(float64(c.G) * variants.Brightness),
+			B: clampUint8(float64(c.B) * variants.Brightness),
+			A: c.A,
+		}
+	})
+
+	outputs := []struct {
+		name string
+		img  image.Image
+	}{
+		{"background_blur.png", blurred},
+		{"background_dim.png", dimmed},
+		{"jacket_card.png", jacketCard(jacket)},
+	}
+	for _, output := range outputs {
+		outputPath := filepath.Join(distDir, output.name)
+		if err := imaging.Save(output.img, outputPath); err != nil {
+			return fmt.Errorf("派生画像 '%s' の保存に失敗しました: %w", output.name, err)
+		}
+		fmt.Printf("派生画像を '%s' に保存しました。\n", outputPath)
+	}
+	return nil
+}
+
+// blurImage はガウスぼかしを掛ける
+// 半径が大きい場合は縮小してからぼかし、元のサイズに戻して処理を軽くする
+func blurImage(img image.Image, radius float64) *image.NRGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := max(1, int(radius/4))
+	if scale == 1 {
+		return imaging.Blur(img, radius)
+	}
+
+	small := imaging.Resize(img, max(1, width/scale), max(1, height/scale), imaging.Linear)
+	small = imaging.Blur(small, radius/float64(scale))
+	return imaging.Resize(small, width, height, imaging.Linear)
+}
+
+// jacketCard はジャケットに白い縁と影を付けた正方形の透過画像を返す
+func jacketCard(jacket image.Image) *image.NRGBA {
+	cardSize := jacketSize + jacketCardBorder*2
+	canvasSize := cardSize + jacketCardShadow*2
+
+	// 影はカードの形を黒で塗ってぼかす
+	shadow := imaging.New(canvasSize, canvasSize, color.Transparent)
+	shadow = imaging.Paste(shadow, imaging.New(cardSize, cardSize, color.NRGBA{A: 0x80}), image.Pt(jacketCardShadow, jacketCardShadow))
+	card := imaging.Blur(shadow, jacketCardShadow/3)
+
+	frame := imaging.New(cardSize, cardSize, color.White)
+	frame = imaging.Overlay(frame, imaging.Resize(jacket, jacketSize, jacketSize, imaging.Lanczos), image.Pt(jacketCardBorder, jacketCardBorder), 1.0)
+	return imaging.Overlay(card, frame, image.Pt(jacketCardShadow, jacketCardShadow), 1.0)
+}
@@ -0,0 +1,55 @@
+package modules
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestBackgroundVariantsWithDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		in, want BackgroundVariants
+	}{
+		{BackgroundVariants{}, BackgroundVariants{BlurRadius: DefaultBlurRadius, Brightness: DefaultDimBrightness}},
+		{BackgroundVariants{BlurRadius: -1, Brightness: -0.2}, BackgroundVariants{BlurRadius: DefaultBlurRadius, Brightness: DefaultDimBrightness}},
+		{BackgroundVariants{BlurRadius: 8, Brightness: 1.5}, BackgroundVariants{BlurRadius: 8, Brightness: DefaultDimBrightness}},
+		{BackgroundVariants{Enabled: true, BlurRadius: 0.5, Brightness: 1}, BackgroundVariants{Enabled: true, BlurRadius: 0.5, Brightness: 1}},
+		{BackgroundVariants{BlurRadius: 40, Brightness: 0.3}, BackgroundVariants{BlurRadius: 40, Brightness: 0.3}},
+	} {
+		if got := tc.in.withDefaults(); got != tc.want {
+			t.Errorf("%+v.withDefaults() = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestJacketCard(t *testing.T) {
+	jacketColor := color.NRGBA{0x40, 0x80, 0xc0, 0xff}
+	// 正方形でないジャケットも正方形のカードになる
+	for _, size := range []image.Point{{740, 740}, {300, 200}} {
+		card := jacketCard(imaging.New(size.X, size.Y, jacketColor))
+
+		canvasSize := jacketSize + jacketCardBorder*2 + jacketCardShadow*2
+		if got := card.Bounds().Size(); got != image.Pt(canvasSize, canvasSize) {
+			t.Fatalf("%v: size = %v, want %d", size, got, canvasSize)
+		}
+
+		border := jacketCardShadow + jacketCardBorder/2
+		center := canvasSize / 2
+		for _, tc := range []struct {
+			name  string
+			x, y  int
+			check func(c color.NRGBA) bool
+		}{
+			{"角は透明", 0, 0, func(c color.NRGBA) bool { return c.A == 0 }},
+			{"影は半透明", center, jacketCardShadow / 2, func(c color.NRGBA) bool { return c.A > 0 && c.A < 0x80 && c.R == 0 }},
+			{"縁は白", border, center, func(c color.NRGBA) bool { return c == color.NRGBA{0xff, 0xff, 0xff, 0xff} }},
+			{"中央はジャケット", center, center, func(c color.NRGBA) bool { return c == jacketColor }},
+		} {
+			if c := card.NRGBAAt(tc.x, tc.y); !tc.check(c) {
+				t.Errorf("%v: %s (%d, %d) = %v", size, tc.name, tc.x, tc.y, c)
+			}
+		}
+	}
+}
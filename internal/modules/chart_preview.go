@@ -0,0 +1,520 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/vector"
+
+	"sekai-overlay-go/internal/utils"
+)
+
+// chartPreviewDirName は譜面プレビューの連番画像の出力先ディレクトリ名
+const chartPreviewDirName = "preview"
+
+// ノーツスピードの範囲とデフォルト値
+const (
+	MinNoteSpeed     = 1.0
+	MaxNoteSpeed     = 12.0
+	DefaultNoteSpeed = 10.0
+)
+
+// lane.png（1920x1080）上のレーンの位置
+// 判定ラインの中心と、レーンの端を延長した消失点の高さ、判定ライン上での1レーンの幅
+const (
+	laneImageCenterX = 960.0
+	laneApexY        = -55.3
+	laneJudgeY       = 855.0
+	laneUnitWidth    = 126.5
+)
+
+// ノーツの描画設定（判定ライン上での大きさの比率）
+const (
+	noteThickness      = 0.02
+	traceThickness     = 0.012
+	connectorSamples   = 24
+	connectorOpacity   = 0.6
+	flickArrowHeight   = 0.8
+	flickArrowMaxWidth = 1.5
+)
+
+// ノーツの色
+var (
+	colorTapNote       = color.NRGBA{0x5e, 0xd8, 0xff, 0xff}
+	colorSlideNote     = color.NRGBA{0x4c, 0xe0, 0x8a, 0xff}
+	colorFlickNote     = color.NRGBA{0xff, 0x5a, 0x8c, 0xff}
+	colorCriticalNote  = color.NRGBA{0xff, 0xcc, 0x33, 0xff}
+	colorDamageNote    = color.NRGBA{0x8a, 0x5a, 0xc8, 0xff}
+	colorNoteHighlight = color.NRGBA{0xff, 0xff, 0xff, 0xb0}
+)
+
+// previewNoteKind はプレビューで描画するノーツの種類
+type previewNoteKind int
+
+const (
+	noteTap previewNoteKind = iota
+	noteTrace
+	noteTick
+	noteDamage
+)
+
+// previewNote はプレビューで描画するノーツ1つ分の情報
+type previewNote struct {
+	Time      float64
+	Lane      float64
+	Size      float64
+	Kind      previewNoteKind
+	Slide     bool
+	Flick     bool
+	Critical  bool
+	Direction float64
+}
+
+// previewConnector はスライドの帯（中継点の間）の情報
+type previewConnector struct {
+	Head, Tail previewNote
+	Ease       int
+	Critical   bool
+}
+
+// ChartPreviewOptions は譜面プレビューの連番画像出力のオプション
+type ChartPreviewOptions struct {
+	Format VideoFormat
+	// NoteSpeed はノーツスピード（1.0〜12.0、0の場合はデフォルト値）
+	NoteSpeed float64
+	// Workers は並列で描画するフレーム数（0の場合はCPU数）
+	Workers int
+}
+
+// chartPreviewRenderer はレーンの上にノーツを描画する
+type chartPreviewRenderer struct {
+	format     VideoFormat
+	lane       *TimelineObject
+	base       *image.RGBA
+	originX    float64
+	originY    float64
+	zoom       float64
+	offset     float64
+	hudStart   int
+	duration   float64
+	notes      []previewNote
+	connectors []previewConnector
+}
+
+// RenderChartPreview はchart.jsonのノーツをlane.pngの上に流れるように描画し、
+// タイムラインのフレーム番号を付けた透過PNGとして出力する
+// レーンの位置・表示期間とノーツのタイミングはmain.objectのレーンとInitSettingsのオブジェクトに合わせる
+func RenderChartPreview(distDir string, opts ChartPreviewOptions) error {
+	fmt.Println("譜面プレビューの連番画像の生成を開始します...")
+
+	noteSpeed := opts.NoteSpeed
+	if noteSpeed == 0 {
+		noteSpeed = DefaultNoteSpeed
+	}
+	if noteSpeed < MinNoteSpeed || noteSpeed > MaxNoteSpeed {
+		return fmt.Errorf("ノーツスピードは%.1f〜%.1fの範囲で指定してください: %.2f", MinNoteSpeed, MaxNoteSpeed, noteSpeed)
+	}
+
+	objectData, err := os.ReadFile(filepath.Join(distDir, "main.object"))
+	if err != nil {
+		return fmt.Errorf("main.objectの読み込みに失敗しました: %w", err)
+	}
+	objectFile, err := ParseObjectFile(string(objectData))
+	if err != nil {
+		return err
+	}
+
+	chartFile, err := os.Open(filepath.Join(distDir, "chart.json"))
+	if err != nil {
+		return fmt.Errorf("chart.jsonの読み込みに失敗しました: %w", err)
+	}
+	defer chartFile.Close()
+	var levelData map[string]interface{}
+	if err := json.NewDecoder(chartFile).Decode(&levelData); err != nil {
+		return fmt.Errorf("chart.jsonの解析に失敗しました: %w", err)
+	}
+
+	renderer, err := newChartPreviewRenderer(objectFile, levelData, opts.Format, noteSpeed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ノーツ: %d個, スライド: %d本, ノーツスピード: %.1f\n", len(renderer.notes), len(renderer.connectors), noteSpeed)
+
+	outputDir := filepath.Join(distDir, chartPreviewDirName)
+	totalFrames := objectFile.EndFrame() + 1
+	render := func(frame int) image.Image {
+		if canvas := renderer.renderFrame(frame); canvas != nil {
+			return canvas
+		}
+		return nil
+	}
+	if err := writeFrameSequence(outputDir, totalFrames, opts.Format, opts.Workers, render); err != nil {
+		return err
+	}
+
+	fmt.Printf("譜面プレビューの連番画像を '%s' に保存しました。(%s, %dフレーム)\n", outputDir, opts.Format, totalFrames)
+	return nil
+}
+
+// newChartPreviewRenderer はmain.objectのレーンの位置と譜面のノーツを読み込んで描画の準備をする
+func newChartPreviewRenderer(objectFile *ObjectFile, levelData map[string]interface{}, format VideoFormat, noteSpeed float64) (*chartPreviewRenderer, error) {
+	inits := objectFile.FindByEffect(effectInitSettings)
+	if len(inits) == 0 {
+		return nil, fmt.Errorf("main.objectに%sが見つかりません", effectInitSettings)
+	}
+
+	// main.objectのパスはAviUtl2用（Wineでは Z:\...）なので、位置だけを使い画像は同梱のものを読み込む
+	var lane *TimelineObject
+	for _, obj := range objectFile.Objects {
+		if effect := obj.Effect("画像ファイル"); effect != nil {
+			file, _ := effect.Get("ファイル")
+			if path.Base(strings.ReplaceAll(file, "\\", "/")) == "lane.png" {
+				lane = obj
+				break
+			}
+		}
+	}
+	if lane == nil {
+		return nil, fmt.Errorf("main.objectにレーンの画像が見つかりません")
+	}
+
+	laneImage, err := imaging.Open(utils.ResourcePath(filepath.Join("assets", "lane", "v3", "lane.png")))
+	if err != nil {
+		return nil, fmt.Errorf("レーンの画像の読み込みに失敗しました: %w", err)
+	}
+
+	standard := lane.Effect("標準描画")
+	zoom := effectFloat(standard, "拡大率", 100) / 100
+	bounds := laneImage.Bounds()
+	width := int(math.Round(float64(bounds.Dx()) * zoom))
+	height := int(math.Round(float64(bounds.Dy()) * zoom))
+
+	r := &chartPreviewRenderer{
+		format:   format,
+		lane:     lane,
+		zoom:     zoom,
+		originX:  float64(format.Width)/2 + effectFloat(standard, "X", 0) - float64(width)/2,
+		originY:  float64(format.Height)/2 + effectFloat(standard, "Y", 0) - float64(height)/2,
+		offset:   effectFloat(inits[0].Effect(effectInitSettings), "offset", 0),
+		hudStart: inits[0].Start(),
+		duration: noteDuration(noteSpeed),
+	}
+
+	// レーンは全フレーム共通なので、出力解像度で1度だけ描画しておく
+	r.base = image.NewRGBA(image.Rect(0, 0, format.Width, format.Height))
+	scaled := imaging.Resize(laneImage, width, height, imaging.Linear)
+	pos := image.Pt(int(math.Round(r.originX)), int(math.Round(r.originY)))
+	draw.Draw(r.base, scaled.Bounds().Add(pos), scaled, image.Point{}, draw.Over)
+
+	r.notes, r.connectors = parsePreviewChart(levelData)
+	if len(r.notes) == 0 {
+		return nil, fmt.Errorf("譜面に描画できるノーツがありません")
+	}
+	return r, nil
+}
+
+// noteDuration はノーツスピードからノーツが出現してから判定ラインに届くまでの秒数を求める
+// 本家と同様に、スピード12で0.35秒、スピード1で4秒になる
+func noteDuration(noteSpeed float64) float64 {
+	t := clampFloat((MaxNoteSpeed-noteSpeed)/(MaxNoteSpeed-MinNoteSpeed), 0, 1)
+	return 0.35 + (4-0.35)*math.Pow(t, 1.31)
+}
+
+// parsePreviewChart は譜面のエンティティからノーツとスライドの帯を抽出する
+// 時間はBPM変更のみを考慮し、ハイスピード（TimeScale）の変化は反映しない
+func parsePreviewChart(levelData map[string]interface{}) ([]previewNote, []previewConnector) {
+	entities, _ := levelData["entities"].([]interface{})
+	bpmChanges := parseBpmChanges(entities)
+
+	named := map[string]previewNote{}
+	var notes []previewNote
+	var connectorEntities []map[string]interface{}
+
+	for _, entity := range entities {
+		entityMap, ok := entity.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		archetype, _ := entityMap["archetype"].(string)
+		if strings.HasSuffix(archetype, "Connector") {
+			connectorEntities = append(connectorEntities, entityMap)
+			continue
+		}
+
+		note, visible, ok := parsePreviewNote(archetype, entityData(entityMap), bpmChanges)
+		if !ok {
+			continue
+		}
+		if name, _ := entityMap["name"].(string); name != "" {
+			named[name] = note
+		}
+		if visible {
+			notes = append(notes, note)
+		}
+	}
+
+	var connectors []previewConnector
+	for _, entityMap := range connectorEntities {
+		data := entityData(entityMap)
+		head, headOK := named[dataRef(data, "head")]
+		tail, tailOK := named[dataRef(data, "tail")]
+		if !headOK || !tailOK || tail.Time <= head.Time {
+			continue
+		}
+		archetype, _ := entityMap["archetype"].(string)
+		connectors = append(connectors, previewConnector{
+			Head:     head,
+			Tail:     tail,
+			Ease:     int(getValueFromData(data, "ease")),
+			Critical: strings.Contains(archetype, "Critical") || head.Critical,
+		})
+	}
+
+	// 奥のノーツから描画するため、時間の遅い順に並べる
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].Time > notes[j].Time })
+	return notes, connectors
+}
+
+// parsePreviewNote はアーキタイプ名からノーツの種類を判定する
+// visibleがfalseのノーツ（非表示の中継点など）はスライドの帯の端点としてのみ使う
+func parsePreviewNote(archetype string, data []map[string]interface{}, bpmChanges []BpmChange) (note previewNote, visible, ok bool) {
+	if !strings.Contains(archetype, "Note") {
+		return previewNote{}, false, false
+	}
+	if _, hasLane := dataValue(data, "lane"); !hasLane {
+		return previewNote{}, false, false
+	}
+
+	note = previewNote{
+		Time:      getTimeFromBpmChanges(bpmChanges, getValueFromData(data, "#BEAT")),
+		Lane:      getValueFromData(data, "lane"),
+		Size:      getValueFromData(data, "size"),
+		Slide:     strings.Contains(archetype, "Slide") || strings.Contains(archetype, "Head") || strings.Contains(archetype, "Tail"),
+		Flick:     strings.Contains(archetype, "Flick"),
+		Critical:  strings.Contains(archetype, "Critical"),
+		Direction: getValueFromData(data, "direction"),
+	}
+	switch {
+	case strings.Contains(archetype, "Damage"):
+		note.Kind = noteDamage
+	case strings.Contains(archetype, "Tick"):
+		note.Kind = noteTick
+	case strings.Contains(archetype, "Trace"):
+		note.Kind = noteTrace
+	default:
+		note.Kind = noteTap
+	}
+
+	visible = !strings.Contains(archetype, "Hidden") && !strings.Contains(archetype, "Ignored") && !strings.Contains(archetype, "Anchor")
+	return note, visible, true
+}
+
+// dataValue はデータ配列から指定された名前の数値を取得する（存在しない場合はfalse）
+func dataValue(data []map[string]interface{}, name string) (float64, bool) {
+	for _, item := range data {
+		if item["name"] == name {
+			value, ok := item["value"].(float64)
+			return value, ok
+		}
+	}
+	return 0, false
+}
+
+// dataRef はデータ配列から指定された名前の参照先のエンティティ名を取得する
+func dataRef(data []map[string]interface{}, name string) string {
+	for _, item := range data {
+		if item["name"] == name {
+			ref, _ := item["ref"].(string)
+			return ref
+		}
+	}
+	return ""
+}
+
+// approach は判定時刻までの残り時間から、判定ラインを1とした奥行きの倍率を返す
+// 本家と同様に、出現時は1.06^-45倍の大きさから指数的に近づく
+func (r *chartPreviewRenderer) approach(remaining float64) float64 {
+	return math.Pow(1.06, -45*remaining/r.duration)
+}
+
+// point はレーン上の位置（中央を0としたレーン単位）と奥行きの倍率を出力画像の座標に変換する
+func (r *chartPreviewRenderer) point(lane, scale float64) (float32, float32) {
+	x := laneImageCenterX + lane*laneUnitWidth*scale
+	y := laneApexY + (laneJudgeY-laneApexY)*scale
+	return float32(r.originX + x*r.zoom), float32(r.originY + y*r.zoom)
+}
+
+// renderFrame は指定フレームのレーンとノーツを描画する（レーンが表示されない場合はnilを返す）
+func (r *chartPreviewRenderer) renderFrame(frame int) *image.RGBA {
+	if frame < r.lane.Start() || frame > r.lane.End() {
+		return nil
+	}
+	now := (float64(frame-r.hudStart) - r.offset) / float64(r.format.FPS)
+
+	canvas := image.NewRGBA(r.base.Rect)
+	copy(canvas.Pix, r.base.Pix)
+	raster := &vector.Rasterizer{}
+
+	for _, connector := range r.connectors {
+		r.drawConnector(canvas, raster, connector, now)
+	}
+	for _, note := range r.notes {
+		remaining := note.Time - now
+		if remaining < 0 || remaining > r.duration {
+			continue
+		}
+		r.drawNote(canvas, raster, note, r.approach(remaining))
+	}
+	return canvas
+}
+
+// drawConnector はスライドの帯のうち、判定ラインから画面奥までの範囲を描画する
+func (r *chartPreviewRenderer) drawConnector(canvas *image.RGBA, raster *vector.Rasterizer, c previewConnector, now float64) {
+	start := math.Max(c.Head.Time, now)
+	end := math.Min(c.Tail.Time, now+r.duration)
+	if start >= end {
+		return
+	}
+
+	left := make([][2]float32, 0, connectorSamples+1)
+	right := make([][2]float32, 0, connectorSamples+1)
+	for i := 0; i <= connectorSamples; i++ {
+		t := start + (end-start)*float64(i)/connectorSamples
+		progress := easeConnector(c.Ease, (t-c.Head.Time)/(c.Tail.Time-c.Head.Time))
+		lane := c.Head.Lane + (c.Tail.Lane-c.Head.Lane)*progress
+		size := c.Head.Size + (c.Tail.Size-c.Head.Size)*progress
+		scale := r.approach(t - now)
+		lx, ly := r.point(lane-size, scale)
+		rx, ry := r.point(lane+size, scale)
+		left = append(left, [2]float32{lx, ly})
+		right = append(right, [2]float32{rx, ry})
+	}
+	for i := len(right) - 1; i >= 0; i-- {
+		left = append(left, right[i])
+	}
+
+	col := colorSlideNote
+	if c.Critical {
+		col = colorCriticalNote
+	}
+	col.A = uint8(float64(col.A) * connectorOpacity)
+	fillPolygon(canvas, raster, left, col)
+}
+
+// easeConnector はスライドの帯の曲がり方（1: 加速、-1: 減速、2・-2: その組み合わせ）を適用する
+func easeConnector(ease int, t float64) float64 {
+	in := func(t float64) float64 { return t * t }
+	out := func(t float64) float64 { return 1 - (1-t)*(1-t) }
+	switch ease {
+	case 1:
+		return in(t)
+	case -1:
+		return out(t)
+	case 2:
+		if t < 0.5 {
+			return in(t*2) / 2
+		}
+		return 0.5 + out(t*2-1)/2
+	case -2:
+		if t < 0.5 {
+			return out(t*2) / 2
+		}
+		return 0.5 + in(t*2-1)/2
+	}
+	return t
+}
+
+// drawNote はノーツを奥行きに合わせた台形で描画する
+func (r *chartPreviewRenderer) drawNote(canvas *image.RGBA, raster *vector.Rasterizer, note previewNote, scale float64) {
+	col := colorTapNote
+	switch {
+	case note.Kind == noteDamage:
+		col = colorDamageNote
+	case note.Critical:
+		col = colorCriticalNote
+	case note.Flick:
+		col = colorFlickNote
+	case note.Slide:
+		col = colorSlideNote
+	}
+
+	thickness := noteThickness
+	if note.Kind == noteTrace {
+		thickness = traceThickness
+	}
+	left, right := note.Lane-note.Size, note.Lane+note.Size
+
+	if note.Kind == noteTick {
+		// 中継点は菱形で描画する
+		x0, y0 := r.point(note.Lane, scale*(1-thickness))
+		x1, y1 := r.point(note.Lane+0.35, scale)
+		x2, y2 := r.point(note.Lane, scale*(1+thickness))
+		x3, y3 := r.point(note.Lane-0.35, scale)
+		fillPolygon(canvas, raster, [][2]float32{{x0, y0}, {x1, y1}, {x2, y2}, {x3, y3}}, col)
+		return
+	}
+
+	fillPolygon(canvas, raster, r.quad(left, right, scale*(1-thickness), scale*(1+thickness)), col)
+	fillPolygon(canvas, raster, r.quad(left+0.15, right-0.15, scale*(1-thickness*0.25), scale*(1+thickness*0.25)), colorNoteHighlight)
+
+	if note.Flick {
+		// フリックの矢印はノーツの上に、向きに合わせて傾けて描画する
+		bx, by := r.point(note.Lane, scale*(1-thickness))
+		unit := laneUnitWidth * scale * r.zoom
+		half := math.Min(note.Size, flickArrowMaxWidth) * 0.55 * unit
+		tipX := float64(bx) + note.Direction*0.5*unit
+		tipY := float64(by) - flickArrowHeight*unit
+		arrow := [][2]float32{
+			{bx - float32(half), by},
+			{float32(tipX), float32(tipY)},
+			{bx + float32(half), by},
+		}
+		fillPolygon(canvas, raster, arrow, col)
+	}
+}
+
+// quad はレーン上の範囲と奥行きの範囲から台形の頂点を求める
+func (r *chartPreviewRenderer) quad(left, right, far, near float64) [][2]float32 {
+	x0, y0 := r.point(left, far)
+	x1, y1 := r.point(right, far)
+	x2, y2 := r.point(right, near)
+	x3, y3 := r.point(left, near)
+	return [][2]float32{{x0, y0}, {x1, y1}, {x2, y2}, {x3, y3}}
+}
+
+// fillPolygon は多角形をアンチエイリアス付きで塗りつぶす
+// ラスタライザは多角形を囲む範囲の大きさだけ確保する
+func fillPolygon(dst *image.RGBA, raster *vector.Rasterizer, points [][2]float32, col color.NRGBA) {
+	if len(points) < 3 {
+		return
+	}
+	minX, minY := points[0][0], points[0][1]
+	maxX, maxY := minX, minY
+	for _, p := range points[1:] {
+		minX, maxX = min(minX, p[0]), max(maxX, p[0])
+		minY, maxY = min(minY, p[1]), max(maxY, p[1])
+	}
+	rect := image.Rect(int(math.Floor(float64(minX))), int(math.Floor(float64(minY))), int(math.Ceil(float64(maxX))), int(math.Ceil(float64(maxY)))).Intersect(dst.Rect)
+	if rect.Empty() {
+		return
+	}
+
+	raster.Reset(rect.Dx(), rect.Dy())
+	offsetX, offsetY := float32(rect.Min.X), float32(rect.Min.Y)
+	raster.MoveTo(points[0][0]-offsetX, points[0][1]-offsetY)
+	for _, p := range points[1:] {
+		raster.LineTo(p[0]-offsetX, p[1]-offsetY)
+	}
+	raster.ClosePath()
+	raster.Draw(dst, rect, image.NewUniform(col), image.Point{})
+}
@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ClipPoint は切り抜き範囲の開始・終了位置を表す構造体
+// Beatがtrueの場合、Valueはビート位置として扱う
+type ClipPoint struct {
+	Value float64
+	Beat  bool
+	Set   bool
+}
+
+// ClipRange はハイライト用の切り抜き範囲を表す構造体
+type ClipRange struct {
+	Start ClipPoint
+	End   ClipPoint
+}
+
+// ParseClipPoint は入力文字列を切り抜き位置に変換する
+// "90.5" のような数値は秒、"128b" のように末尾にbを付けた値はビートとして扱う
+func ParseClipPoint(input string) (ClipPoint, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return ClipPoint{}, nil
+	}
+
+	beat := false
+	lower := strings.ToLower(input)
+	if strings.HasSuffix(lower, "b") {
+		beat = true
+		lower = strings.TrimSuffix(lower, "b")
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+	if err != nil || value < 0 {
+		return ClipPoint{}, fmt.Errorf("無効な切り抜き位置です: %s", input)
+	}
+	return ClipPoint{Value: value, Beat: beat, Set: true}, nil
+}
+
+// String はClipPointを表示用の文字列に変換する
+func (p ClipPoint) String() string {
+	if !p.Set {
+		return "-"
+	}
+	if p.Beat {
+		return fmt.Sprintf("%gビート", p.Value)
+	}
+	return fmt.Sprintf("%g秒", p.Value)
+}
+
+// IsSet は切り抜き範囲が指定されているかを返す
+func (c ClipRange) IsSet() bool {
+	return c.Start.Set || c.End.Set
+}
+
+// Resolve は譜面のタイミング情報を使って切り抜き範囲を秒に変換する
+// 終了位置が指定されていない場合は最後のノーツの時間を返す
+func (c ClipRange) Resolve(timing ChartTiming) (float64, float64, error) {
+	start := 0.0
+	end := timing.LastNoteTime
+
+	var err error
+	if c.Start.Set {
+		if start, err = c.Start.seconds(timing); err != nil {
+			return 0, 0, err
+		}
+	}
+	if c.End.Set {
+		if end, err = c.End.seconds(timing); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if end <= start {
+		return 0, 0, fmt.Errorf("切り抜きの終了位置 (%.3f秒) が開始位置 (%.3f秒) より前です", end, start)
+	}
+	return start, end, nil
+}
+
+// seconds はClipPointを秒に変換する
+func (p ClipPoint) seconds(timing ChartTiming) (float64, error) {
+	if p.Beat {
+		if len(timing.BpmChanges) == 0 {
+			return 0, fmt.Errorf("譜面にBPMの情報がないためビート位置 (%s) を秒に変換できません", p)
+		}
+		return timing.TimeAtBeat(p.Value), nil
+	}
+	return p.Value, nil
+}
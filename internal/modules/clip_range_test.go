@@ -0,0 +1,49 @@
+package modules
+
+import (
+	"math"
+	"testing"
+)
+
+// bpmEntity はBPM変更のエンティティを作る
+func bpmEntity(beat, bpm float64) map[string]interface{} {
+	return map[string]interface{}{
+		"archetype": "#BPM_CHANGE",
+		"data": []interface{}{
+			map[string]interface{}{"name": "#BEAT", "value": beat},
+			map[string]interface{}{"name": "#BPM", "value": bpm},
+		},
+	}
+}
+
+func TestClipRangeResolveWithoutNotes(t *testing.T) {
+	// ノーツがない譜面でもBPMの情報からビート位置を秒に変換できる
+	levelData := map[string]interface{}{"entities": []interface{}{bpmEntity(0, 120)}}
+	_, timing := calculateScoreFrames(map[string]interface{}{"rating": 20.0}, levelData, 200000)
+
+	start, _ := ParseClipPoint("8b")
+	end, _ := ParseClipPoint("16b")
+	gotStart, gotEnd, err := ClipRange{Start: start, End: end}.Resolve(timing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(gotStart-4) > 1e-9 || math.Abs(gotEnd-8) > 1e-9 {
+		t.Errorf("Resolve = %v, %v, want 4, 8", gotStart, gotEnd)
+	}
+}
+
+func TestClipRangeResolveWithoutBpm(t *testing.T) {
+	_, timing := calculateScoreFrames(map[string]interface{}{}, map[string]interface{}{}, 200000)
+
+	start, _ := ParseClipPoint("8b")
+	if _, _, err := (ClipRange{Start: start}).Resolve(timing); err == nil {
+		t.Error("BPMの情報がないのにビート位置を変換できました")
+	}
+
+	// 秒で指定した場合はBPMの情報がなくても変換できる
+	start, _ = ParseClipPoint("1.5")
+	end, _ := ParseClipPoint("3")
+	if _, _, err := (ClipRange{Start: start, End: end}).Resolve(timing); err != nil {
+		t.Error(err)
+	}
+}
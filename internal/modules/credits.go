@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Credits は楽曲のクレジット（作詞・作曲・編曲・ボーカル）を表す構造体
+type Credits struct {
+	Words   string
+	Music   string
+	Arrange string
+	Vocal   string
+}
+
+// クレジットの区切りとラベルの判定に使用する正規表現
+var (
+	creditSeparatorPattern = regexp.MustCompile(`\r?\n|\s+/\s+|／|\s*\|\s*|\s*｜\s*`)
+	creditLabelPattern     = regexp.MustCompile(`^\s*[\[【(（]?\s*([^:：]{1,24}?)\s*[\]】)）]?\s*[:：]\s*(.+?)\s*$`)
+	creditVocalPattern     = regexp.MustCompile(`^\s*(?i:vo(?:cal)?s?)\s*[.．]\s*(.+?)\s*$`)
+	creditFeatPattern      = regexp.MustCompile(`^(.+?)\s+(?i:feat\.?|ft\.)\s+(.+)$`)
+	// creditLabelSeparatorPattern は「作詞・作曲」「Words & Music」のように複数の項目をまとめたラベルの区切り
+	creditLabelSeparatorPattern = regexp.MustCompile(`\s*(?:[・&＆、,，+＋]|\band\b)\s*`)
+)
+
+// creditRole はクレジットの項目
+type creditRole int
+
+const (
+	roleWords creditRole = iota
+	roleMusic
+	roleArrange
+	roleVocal
+)
+
+// creditLabelRoles はラベルの表記（小文字）と項目の対応
+// ラベル全体、または区切られた各部分が一致する場合のみ項目として扱う（「原曲」「選曲」などは対象外）
+var creditLabelRoles = map[string]creditRole{
+	"作詞": roleWords, "作詞者": roleWords, "詞": roleWords,
+	"lyrics": roleWords, "lyric": roleWords, "lyricist": roleWords, "words": roleWords, "word": roleWords,
+	"作曲": roleMusic, "作曲者": roleMusic, "曲": roleMusic,
+	"music": roleMusic, "composer": roleMusic, "composed": roleMusic, "composition": roleMusic,
+	"編曲": roleArrange, "編曲者": roleArrange,
+	"arrange": roleArrange, "arranged": roleArrange, "arrangement": roleArrange, "arranger": roleArrange,
+	"歌": roleVocal, "唄": roleVocal, "ボーカル": roleVocal, "ヴォーカル": roleVocal,
+	"vocal": roleVocal, "vocals": roleVocal, "vo": roleVocal, "vo.": roleVocal, "singer": roleVocal,
+}
+
+// joinedCreditLabels は区切りなしで続けて書かれることがある日本語のラベル（「作詞作曲」など）
+var joinedCreditLabels = []struct {
+	prefix string
+	role   creditRole
+}{
+	{"作詞", roleWords},
+	{"作曲", roleMusic},
+	{"編曲", roleArrange},
+}
+
+// ExtractCredits はlevel.jsonの内容からクレジットを抽出する
+// itemのartistsとdescriptionに含まれる「作詞:」「作曲:」「編曲:」「Vo.」などの表記を解析する
+func ExtractCredits(levelData map[string]interface{}) Credits {
+	var credits Credits
+
+	itemData, _ := levelData["item"].(map[string]interface{})
+	artists := getStringValue(itemData, "artists", nil, "", "")
+	description := getStringValue(levelData, "description", itemData, "description", "")
+
+	labeled := parseCreditText(artists, &credits)
+	parseCreditText(description, &credits)
+
+	// ラベルのないartistsは作曲者（feat.表記があればボーカルも）として扱う
+	if !labeled && artists != "" {
+		name := strings.TrimSpace(artists)
+		if match := creditFeatPattern.FindStringSubmatch(name); match != nil {
+			name = match[1]
+			setCredit(&credits.Vocal, match[2])
+		}
+		setCredit(&credits.Music, name)
+	}
+
+	return credits
+}
+
+// parseCreditText はテキストからラベル付きのクレジットを読み取り、未設定の項目に反映する
+// ラベル付きの項目が1つでも見つかった場合はtrueを返す
+func parseCreditText(text string, credits *Credits) bool {
+	found := false
+	for _, segment := range creditSeparatorPattern.Split(text, -1) {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+
+		if match := creditVocalPattern.FindStringSubmatch(segment); match != nil {
+			setCredit(&credits.Vocal, match[1])
+			found = true
+			continue
+		}
+
+		match := creditLabelPattern.FindStringSubmatch(segment)
+		if match == nil {
+			continue
+		}
+		for _, role := range creditLabelRolesOf(match[1]) {
+			setCredit(credits.field(role), match[2])
+			found = true
+		}
+	}
+	return found
+}
+
+// setCredit は未設定の場合のみクレジットを設定する（先に見つかった値を優先する）
+func setCredit(target *string, value string) {
+	value = strings.TrimSpace(value)
+	if *target == "" && value != "" {
+		*target = value
+	}
+}
+
+// creditLabelRolesOf はラベルに対応する項目を返す（該当しない場合は空）
+func creditLabelRolesOf(label string) []creditRole {
+	var roles []creditRole
+	for _, part := range creditLabelSeparatorPattern.Split(strings.ToLower(label), -1) {
+		// 「Music by」「Arranged by」のような英語の表記
+		part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), " by"))
+		if role, ok := creditLabelRoles[part]; ok {
+			roles = append(roles, role)
+			continue
+		}
+		roles = append(roles, joinedCreditLabelRoles(part)...)
+	}
+	return roles
+}
+
+// joinedCreditLabelRoles は「作詞作曲」「作詞・作曲・編曲者」のように続けて書かれたラベルを先頭から読み取る
+// 読み取れない文字が残る場合は該当しないものとして扱う
+func joinedCreditLabelRoles(label string) []creditRole {
+	var roles []creditRole
+	rest := strings.TrimSuffix(label, "者")
+	for rest != "" {
+		matched := false
+		for _, joined := range joinedCreditLabels {
+			if strings.HasPrefix(rest, joined.prefix) {
+				roles = append(roles, joined.role)
+				rest = rest[len(joined.prefix):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	}
+	return roles
+}
+
+// field は項目に対応するフィールドを返す
+func (c *Credits) field(role creditRole) *string {
+	switch role {
+	case roleWords:
+		return &c.Words
+	case roleMusic:
+		return &c.Music
+	case roleArrange:
+		return &c.Arrange
+	}
+	return &c.Vocal
+}
@@ -0,0 +1,86 @@
+package modules
+
+import "testing"
+
+func TestExtractCredits(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		artists     string
+		description string
+		want        Credits
+	}{
+		{
+			name:        "全角コロンと改行",
+			description: "作詞：A\n作曲：B\n編曲：C\nVo. D",
+			want:        Credits{Words: "A", Music: "B", Arrange: "C", Vocal: "D"},
+		},
+		{
+			name:    "半角コロンとスラッシュ区切り",
+			artists: "作詞:A / 作曲:B / 編曲:C",
+			want:    Credits{Words: "A", Music: "B", Arrange: "C"},
+		},
+		{
+			name:        "まとめたラベル",
+			description: "作詞・作曲：A\n編曲：B",
+			want:        Credits{Words: "A", Music: "A", Arrange: "B"},
+		},
+		{
+			name:        "区切りなしで続くラベル",
+			description: "作詞作曲編曲：A",
+			want:        Credits{Words: "A", Music: "A", Arrange: "A"},
+		},
+		{
+			name:        "括弧付きのラベル",
+			description: "【作詞】：A｜【作曲】：B",
+			want:        Credits{Words: "A", Music: "B"},
+		},
+		{
+			name:        "英語のラベル",
+			description: "Lyrics & Music: A | Arranged by: B | Vocals: C",
+			want:        Credits{Words: "A", Music: "A", Arrange: "B", Vocal: "C"},
+		},
+		{
+			name:        "歌のラベル",
+			description: "歌：A",
+			want:        Credits{Vocal: "A"},
+		},
+		{
+			name:        "Vo.表記",
+			description: "Vo.初音ミク",
+			want:        Credits{Vocal: "初音ミク"},
+		},
+		{
+			name:        "原曲・選曲は作曲者ではない",
+			description: "原曲：X\n選曲：Y\n作曲：Z",
+			want:        Credits{Music: "Z"},
+		},
+		{
+			name:        "voを含むだけのラベルはボーカルではない",
+			description: "Voice：X\nMovie：Y\nVocaloid Producer：Z",
+			want:        Credits{},
+		},
+		{
+			name:    "ラベルのないartistsは作曲者",
+			artists: "A feat. 初音ミク",
+			want:    Credits{Music: "A", Vocal: "初音ミク"},
+		},
+		{
+			name:        "先に見つかった値を優先",
+			artists:     "作曲：A",
+			description: "作曲：B\n作詞：C",
+			want:        Credits{Words: "C", Music: "A"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			levelData := map[string]interface{}{
+				"item": map[string]interface{}{
+					"artists":     tc.artists,
+					"description": tc.description,
+				},
+			}
+			if got := ExtractCredits(levelData); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,130 +1,241 @@
 package modules
 
 import (
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"image"
+	_ "image/gif"
 	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"sekai-overlay-go/internal/config"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
-// DownloadAndPrepareAssets は指定サーバーから譜面データをダウンロードし、ジャケットをリサイズする
-func DownloadAndPrepareAssets(prefix, idPart, distDir string) (string, error) {
-	baseURL, exists := config.ServerMap[prefix]
-	if !exists {
-		return "", fmt.Errorf("サポートされていないサーバー接頭辞です: %s", prefix)
-	}
-
-	apiURL := fmt.Sprintf("%s%s-%s", baseURL, prefix, idPart)
-	fullLevelID := fmt.Sprintf("%s-%s", prefix, idPart)
+const (
+	// jacketSize はジャケット画像の一辺のサイズ
+	jacketSize = 512
+	// coverSourceFile はダウンロードしたカバー画像の保存名（形式を判別するまで拡張子は付けない）
+	coverSourceFile = "cover_source"
+)
 
-	fmt.Printf("APIにアクセスしています: %s\n", apiURL)
+// AssetOverrides はサーバーの代わりに使うローカルのファイル（空の場合はダウンロードする）
+type AssetOverrides struct {
+	Jacket string
+	Audio  string
+}
 
-	// APIリクエスト
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return "", fmt.Errorf("APIリクエストに失敗しました: %w", err)
-	}
-	defer resp.Body.Close()
+// AssetSources はジャケットとBGMをどこから取得したかを表す
+type AssetSources struct {
+	Jacket ResourceSource
+	Audio  ResourceSource
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("APIレスポンスエラー: %d", resp.StatusCode)
-	}
+// DownloadAndPrepareAssets は指定サーバーから譜面データをダウンロードし、ジャケットをリサイズする
+// overridesにファイルが指定されている場合は、ダウンロードの代わりにそのファイルを使う
+func DownloadAndPrepareAssets(prefix, idPart, distDir string, overrides AssetOverrides) (string, AssetSources, error) {
+	fullLevelID := fmt.Sprintf("%s-%s", prefix, idPart)
+	var sources AssetSources
 
-	var apiResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return "", fmt.Errorf("JSONデコードに失敗しました: %w", err)
+	apiResponse, err := fetchLevelDetails(prefix, idPart)
+	if err != nil {
+		return "", sources, err
 	}
 
 	// ディレクトリ作成
 	if err := os.MkdirAll(distDir, 0755); err != nil {
-		return "", fmt.Errorf("ディレクトリ作成に失敗しました: %w", err)
+		return "", sources, fmt.Errorf("ディレクトリ作成に失敗しました: %w", err)
 	}
 
 	// level.json保存
 	levelPath := filepath.Join(distDir, "level.json")
 	levelFile, err := os.Create(levelPath)
 	if err != nil {
-		return "", fmt.Errorf("level.json作成に失敗しました: %w", err)
+		return "", sources, fmt.Errorf("level.json作成に失敗しました: %w", err)
 	}
 	defer levelFile.Close()
 
 	encoder := json.NewEncoder(levelFile)
 	encoder.SetIndent("", "    ")
 	if err := encoder.Encode(apiResponse); err != nil {
-		return "", fmt.Errorf("level.json書き込みに失敗しました: %w", err)
+		return "", sources, fmt.Errorf("level.json書き込みに失敗しました: %w", err)
 	}
 
 	fmt.Printf("ファイルを '%s' に保存します。\n", distDir)
 
 	item, ok := apiResponse["item"].(map[string]interface{})
 	if !ok {
-		return "", fmt.Errorf("APIレスポンスにitemフィールドが見つかりません")
+		return "", sources, fmt.Errorf("APIレスポンスにitemフィールドが見つかりません")
 	}
 
 	// ジャケットダウンロードとリサイズ
-	cover, ok := item["cover"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("cover情報が見つかりません")
+	coverPath := filepath.Join(distDir, coverSourceFile)
+	if overrides.Jacket != "" {
+		fmt.Printf("ローカルのジャケット画像 '%s' を使用します。\n", overrides.Jacket)
+		if err := copyFile(overrides.Jacket, coverPath); err != nil {
+			return "", sources, fmt.Errorf("ジャケット画像のコピーに失敗しました: %w", err)
+		}
+		sources.Jacket = ResourceSource{Origin: SourceLocal, Location: overrides.Jacket}
+	} else {
+		coverURL, err := itemURL(item, "cover")
+		if err != nil {
+			return "", sources, err
+		}
+		if err := downloadFile(coverURL, coverPath); err != nil {
+			return "", sources, fmt.Errorf("ジャケットダウンロードに失敗しました: %w", err)
+		}
+		sources.Jacket = ResourceSource{Origin: SourceServer, Location: coverURL}
 	}
-	coverURL, ok := cover["url"].(string)
-	if !ok {
-		return "", fmt.Errorf("cover URLが見つかりません")
+
+	if err := prepareJacket(coverPath, distDir); err != nil {
+		return "", sources, fmt.Errorf("ジャケットの変換に失敗しました: %w", err)
 	}
+	sources.Jacket.File = filepath.Base(jacketImagePath(distDir))
 
-	jacketPath := filepath.Join(distDir, "jacket.jpg")
-	if err := downloadFile(coverURL, jacketPath); err != nil {
-		return "", fmt.Errorf("ジャケットダウンロードに失敗しました: %w", err)
+	// BGMダウンロード（ローカルのファイルは拡張子を保ってコピーする）
+	if err := removeMusicFiles(distDir); err != nil {
+		return "", sources, fmt.Errorf("以前のBGMの削除に失敗しました: %w", err)
+	}
+	if overrides.Audio != "" {
+		fmt.Printf("ローカルの音源 '%s' を使用します。\n", overrides.Audio)
+		ext := strings.ToLower(filepath.Ext(overrides.Audio))
+		if ext == "" {
+			ext = ".mp3"
+		}
+		musicPath := filepath.Join(distDir, "music"+ext)
+		if err := copyFile(overrides.Audio, musicPath); err != nil {
+			return "", sources, fmt.Errorf("音源のコピーに失敗しました: %w", err)
+		}
+		sources.Audio = ResourceSource{Origin: SourceLocal, Location: overrides.Audio, File: filepath.Base(musicPath)}
+	} else {
+		bgmURL, err := itemURL(item, "bgm")
+		if err != nil {
+			return "", sources, err
+		}
+		musicPath := filepath.Join(distDir, "music.mp3")
+		if err := downloadFile(bgmURL, musicPath); err != nil {
+			return "", sources, fmt.Errorf("BGMダウンロードに失敗しました: %w", err)
+		}
+		sources.Audio = ResourceSource{Origin: SourceServer, Location: bgmURL, File: filepath.Base(musicPath)}
 	}
 
-	if err := resizeJacket(jacketPath); err != nil {
-		return "", fmt.Errorf("ジャケットリサイズに失敗しました: %w", err)
+	// チャートデータダウンロードと解凍
+	dataURL, err := itemURL(item, "data")
+	if err != nil {
+		return "", sources, err
 	}
 
-	// BGMダウンロード
-	bgm, ok := item["bgm"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("bgm情報が見つかりません")
+	chartGzPath := filepath.Join(distDir, "chart.json.gz")
+	if err := downloadFile(dataURL, chartGzPath); err != nil {
+		return "", sources, fmt.Errorf("チャートデータダウンロードに失敗しました: %w", err)
 	}
-	bgmURL, ok := bgm["url"].(string)
-	if !ok {
-		return "", fmt.Errorf("bgm URLが見つかりません")
+
+	chartPath := filepath.Join(distDir, "chart.json")
+	if err := unzipGz(chartGzPath, chartPath); err != nil {
+		return "", sources, fmt.Errorf("チャートデータ解凍に失敗しました: %w", err)
 	}
 
-	musicPath := filepath.Join(distDir, "music.mp3")
-	if err := downloadFile(bgmURL, musicPath); err != nil {
-		return "", fmt.Errorf("BGMダウンロードに失敗しました: %w", err)
+	return fullLevelID, sources, nil
+}
+
+// FetchLevelDetails は譜面ID (例: chcy-XXXX) から譜面の詳細情報を取得する
+func FetchLevelDetails(fullLevelID string) (map[string]interface{}, error) {
+	index := strings.LastIndex(fullLevelID, "-")
+	if index <= 0 || index == len(fullLevelID)-1 {
+		return nil, fmt.Errorf("無効な譜面ID形式です (例: chcy-test-1)")
 	}
+	return fetchLevelDetails(fullLevelID[:index], fullLevelID[index+1:])
+}
 
-	// チャートデータダウンロードと解凍
-	data, ok := item["data"].(map[string]interface{})
+// fetchLevelDetails はサーバーのAPIから譜面の詳細情報を取得する
+func fetchLevelDetails(prefix, idPart string) (map[string]interface{}, error) {
+	baseURL, exists := config.ServerMap[prefix]
+	if !exists {
+		return nil, fmt.Errorf("サポートされていないサーバー接頭辞です: %s", prefix)
+	}
+
+	apiURL := fmt.Sprintf("%s%s-%s", baseURL, prefix, idPart)
+	fmt.Printf("APIにアクセスしています: %s\n", apiURL)
+
+	// APIリクエスト
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("APIリクエストに失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("APIレスポンスエラー: %d", resp.StatusCode)
+	}
+
+	var apiResponse map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("JSONデコードに失敗しました: %w", err)
+	}
+	return apiResponse, nil
+}
+
+// itemURL は譜面情報の項目（cover/bgm/data）のURLを取得する
+func itemURL(item map[string]interface{}, key string) (string, error) {
+	resource, ok := item[key].(map[string]interface{})
 	if !ok {
-		return "", fmt.Errorf("data情報が見つかりません")
+		return "", fmt.Errorf("%s情報が見つかりません", key)
 	}
-	dataURL, ok := data["url"].(string)
+	url, ok := resource["url"].(string)
 	if !ok {
-		return "", fmt.Errorf("data URLが見つかりません")
+		return "", fmt.Errorf("%s URLが見つかりません", key)
 	}
+	return url, nil
+}
 
-	chartGzPath := filepath.Join(distDir, "chart.json.gz")
-	if err := downloadFile(dataURL, chartGzPath); err != nil {
-		return "", fmt.Errorf("チャートデータダウンロードに失敗しました: %w", err)
+// MusicPath は出力フォルダのBGMのパスを返す（ローカルの音源を使った場合は拡張子が異なる）
+func MusicPath(distDir string) string {
+	if matches, _ := filepath.Glob(filepath.Join(distDir, "music.*")); len(matches) > 0 {
+		return matches[0]
 	}
+	return filepath.Join(distDir, "music.mp3")
+}
 
-	chartPath := filepath.Join(distDir, "chart.json")
-	if err := unzipGz(chartGzPath, chartPath); err != nil {
-		return "", fmt.Errorf("チャートデータ解凍に失敗しました: %w", err)
+// removeMusicFiles は以前の生成で残ったBGMを削除する
+func removeMusicFiles(distDir string) error {
+	matches, err := filepath.Glob(filepath.Join(distDir, "music.*"))
+	if err != nil {
+		return err
 	}
+	for _, path := range matches {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return fullLevelID, nil
+// copyFile はローカルのファイルをコピーする
+func copyFile(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dest, src); err != nil {
+		dest.Close()
+		return err
+	}
+	return dest.Close()
 }
 
 // downloadFile はファイルをダウンロードする
@@ -149,42 +260,117 @@ func downloadFile(url, destPath string) error {
 	return err
 }
 
-// resizeJacket はジャケット画像をリサイズする
-func resizeJacket(imagePath string) error {
-	file, err := os.Open(imagePath)
+// prepareJacket はダウンロードしたカバー画像の形式を判別し、512x512のjacket.jpgを出力する
+// 透過のある画像はjacket.pngも出力し、jacket.jpgには黒で塗りつぶした画像を保存する
+func prepareJacket(sourcePath, distDir string) error {
+	data, err := os.ReadFile(sourcePath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	img, format, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return err
+		return fmt.Errorf("カバー画像の形式に対応していません (JPEG/PNG/GIF/WebP): %w", err)
+	}
+
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	fmt.Printf("  -> カバー画像: %s %dx%d\n", strings.ToUpper(format), width, height)
+	if width < jacketSize || height < jacketSize {
+		fmt.Printf("警告: カバー画像 (%dx%d) が%dx%dより小さいため、拡大されてぼやける可能性があります。\n", width, height, jacketSize, jacketSize)
 	}
+	if width != height {
+		fmt.Printf("警告: カバー画像が正方形ではないため、縦横比が変わります。\n")
+	}
+
+	transparent := hasTransparency(img)
+	resized := width != jacketSize || height != jacketSize
+
+	jpegPath := filepath.Join(distDir, "jacket.jpg")
+	pngPath := filepath.Join(distDir, "jacket.png")
 
-	// サイズが512x512でない場合のみリサイズ
-	targetSize := 512
-	if img.Bounds().Dx() == targetSize && img.Bounds().Dy() == targetSize {
-		return nil
+	// 512x512の不透明なJPEGはそのまま使う
+	if format == "jpeg" && !resized && !transparent {
+		if err := os.Rename(sourcePath, jpegPath); err != nil {
+			return err
+		}
+		return removeIfExists(pngPath)
 	}
 
-	fmt.Printf("  -> jacket.jpgを%dx%dにリサイズしています...\n", targetSize, targetSize)
+	jacket := image.NewNRGBA(image.Rect(0, 0, jacketSize, jacketSize))
+	if resized {
+		fmt.Printf("  -> ジャケットを%dx%dにリサイズしています...\n", jacketSize, jacketSize)
+		draw.CatmullRom.Scale(jacket, jacket.Bounds(), img, img.Bounds(), draw.Src, nil)
+	} else {
+		draw.Draw(jacket, jacket.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+
+	// JPEGは透過を保持できないので黒の上に合成する
+	flattened := image.NewRGBA(jacket.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), jacket, image.Point{}, draw.Over)
+	if err := writeImageFile(jpegPath, func(w io.Writer) error {
+		return jpeg.Encode(w, flattened, &jpeg.Options{Quality: 95})
+	}); err != nil {
+		return err
+	}
+
+	if transparent {
+		fmt.Println("  -> 透過を保持するためjacket.pngも出力します")
+		if err := writeImageFile(pngPath, func(w io.Writer) error {
+			return png.Encode(w, jacket)
+		}); err != nil {
+			return err
+		}
+	} else if err := removeIfExists(pngPath); err != nil {
+		return err
+	}
+	return os.Remove(sourcePath)
+}
 
-	// 新しい画像を作成
-	dst := image.NewRGBA(image.Rect(0, 0, targetSize, targetSize))
+// jacketImagePath は透過を保持したjacket.pngがあればそのパスを、なければjacket.jpgのパスを返す
+func jacketImagePath(distDir string) string {
+	pngPath := filepath.Join(distDir, "jacket.png")
+	if _, err := os.Stat(pngPath); err == nil {
+		return pngPath
+	}
+	return filepath.Join(distDir, "jacket.jpg")
+}
 
-	// リサイズ
-	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+// hasTransparency は画像に不透明でないピクセルがあるか判定する
+func hasTransparency(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return !opaque.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	// ファイルを書き込みモードで開き直す
-	file, err = os.Create(imagePath)
+// writeImageFile はファイルを作成してencodeで書き込む
+func writeImageFile(path string, encode func(io.Writer) error) error {
+	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
 
-	// JPEGとして保存
-	return jpeg.Encode(file, dst, &jpeg.Options{Quality: 95})
+// removeIfExists は以前の生成で残ったファイルがあれば削除する
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 // unzipGz はgzファイルを解凍する
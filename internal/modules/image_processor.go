@@ -3,9 +3,10 @@ package modules
 import (
 	"fmt"
 	"image"
-	"image/color"
 	"math"
 	"path/filepath"
+	"runtime"
+	"sync"
 
 	"sekai-overlay-go/internal/utils"
 
@@ -13,10 +14,12 @@ import (
 )
 
 // GenerateBackgroundImage は背景画像を生成する
-func GenerateBackgroundImage(levelID, version, distDir string) error {
+// versionはassets/background内の背景レイアウト名（"3"のような数字だけの指定も可）
+// filterはジャケットを射影変換するときの補間方法、variantsはぼかし・暗くした背景とジャケットカードの設定
+func GenerateBackgroundImage(levelID, version, distDir string, filter ResampleFilter, variants BackgroundVariants) error {
 	fmt.Println("背景画像の生成を開始します...")
 
-	coverImagePath := filepath.Join(distDir, "jacket.jpg")
+	coverImagePath := jacketImagePath(distDir)
 	outputImagePath := filepath.Join(distDir, "background.png")
 
 	// カバー画像を読み込み
@@ -25,18 +28,13 @@ func GenerateBackgroundImage(levelID, version, distDir string) error {
 		return fmt.Errorf("カバー画像の読み込みに失敗しました: %w", err)
 	}
 
-	var finalImage *image.NRGBA
-
-	// バージョンに応じてレンダリング
-	switch version {
-	case "3":
-		finalImage, err = renderV3(targetImage)
-	case "1":
-		finalImage, err = renderV1(targetImage)
-	default:
-		return fmt.Errorf("バージョン '%s' は現在サポートされていません", version)
+	// 背景レイアウトに従って合成
+	layout, err := LoadBackgroundLayout(version)
+	if err != nil {
+		return err
 	}
 
+	finalImage, err := layout.Render(targetImage, filter)
 	if err != nil {
 		return fmt.Errorf("背景画像の生成に失敗しました: %w", err)
 	}
@@ -47,164 +45,29 @@ func GenerateBackgroundImage(levelID, version, distDir string) error {
 	}
 
 	fmt.Printf("背景画像を '%s' に保存しました。\n", outputImagePath)
-	return nil
+	return writeBackgroundVariants(finalImage, targetImage, distDir, variants)
 }
 
-// renderV3 はv3の背景画像を生成する
-func renderV3(targetImage image.Image) (*image.NRGBA, error) {
-	// アセット画像の読み込み
-	base, err := loadAssetImage("assets/background/v3/base.png")
-	if err != nil {
-		return nil, err
-	}
-
-	bottom, err := loadAssetImage("assets/background/v3/bottom.png")
-	if err != nil {
-		return nil, err
-	}
-
-	centerCover, err := loadAssetImage("assets/background/v3/center_cover.png")
-	if err != nil {
-		return nil, err
-	}
-
-	centerMask, err := loadAssetImage("assets/background/v3/center_mask.png")
-	if err != nil {
-		return nil, err
-	}
+// GeneratePortraitBackground は生成済みの背景画像から縦向き(9:16)レイアウト用の背景画像を切り出す
+// 出力サイズは1080x1920基準で、解像度に応じた拡大はエイリアス側で行う
+func GeneratePortraitBackground(distDir string) error {
+	backgroundPath := filepath.Join(distDir, "background.png")
+	outputImagePath := filepath.Join(distDir, "background_portrait.png")
 
-	sideCover, err := loadAssetImage("assets/background/v3/side_cover.png")
+	background, err := imaging.Open(backgroundPath)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("背景画像の読み込みに失敗しました: %w", err)
 	}
 
-	sideMask, err := loadAssetImage("assets/background/v3/side_mask.png")
-	if err != nil {
-		return nil, err
-	}
+	// センタージャケットを中心に縦長に切り出す
+	portrait := imaging.Fill(background, BaseHeight, BaseWidth, imaging.Center, imaging.Lanczos)
 
-	windows, err := loadAssetImage("assets/background/v3/windows.png")
-	if err != nil {
-		return nil, err
+	if err := imaging.Save(portrait, outputImagePath); err != nil {
+		return fmt.Errorf("縦向き背景画像の保存に失敗しました: %w", err)
 	}
 
-	baseBounds := base.Bounds()
-	baseSize := image.Rect(0, 0, baseBounds.Dx(), baseBounds.Dy())
-
-	// サイドジャケットの生成
-	sideJackets := imaging.New(baseBounds.Dx(), baseBounds.Dy(), image.Transparent)
-
-	// モーフィング処理（簡易実装）
-	leftNormal := morphImage(targetImage, []image.Point{{566, 161}, {1183, 134}, {633, 731}, {1226, 682}}, baseSize)
-
-	rightNormal := morphImage(targetImage, []image.Point{{966, 104}, {1413, 72}, {954, 525}, {1390, 524}}, baseSize)
-
-	leftMirror := morphImage(targetImage, []image.Point{{633, 1071}, {1256, 1045}, {598, 572}, {1197, 569}}, baseSize)
-
-	rightMirror := morphImage(targetImage, []image.Point{{954, 1122}, {1393, 1167}, {942, 702}, {1366, 717}}, baseSize)
-
-	sideJackets = imaging.Overlay(sideJackets, leftNormal, image.Point{}, 1.0)
-	sideJackets = imaging.Overlay(sideJackets, rightNormal, image.Point{}, 1.0)
-	sideJackets = imaging.Overlay(sideJackets, leftMirror, image.Point{}, 1.0)
-	sideJackets = imaging.Overlay(sideJackets, rightMirror, image.Point{}, 1.0)
-	sideJackets = imaging.Overlay(sideJackets, sideCover, image.Point{}, 1.0)
-
-	// センタージャケットの生成
-	center := imaging.New(baseBounds.Dx(), baseBounds.Dy(), image.Transparent)
-	centerNormal := morphImage(targetImage, []image.Point{{824, 227}, {1224, 227}, {833, 608}, {1216, 608}}, baseSize)
-
-	centerMirror := morphImage(targetImage, []image.Point{{830, 1017}, {1214, 1017}, {833, 676}, {1216, 676}}, baseSize)
-
-	center = imaging.Overlay(center, centerNormal, image.Point{}, 1.0)
-	center = imaging.Overlay(center, centerMirror, image.Point{}, 1.0)
-	center = imaging.Overlay(center, centerCover, image.Point{}, 1.0)
-
-	// マスキング処理
-	sideJackets = applyMask(sideJackets, sideMask)
-
-	center = applyMask(center, centerMask)
-
-	// 最終的な合成
-	finalImage := imaging.Clone(base)
-
-	finalImage = imaging.Overlay(finalImage, sideJackets, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, sideCover, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, windows, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, center, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, bottom, image.Point{}, 1.0)
-
-	return finalImage, nil
-}
-
-// renderV1 はv1の背景画像を生成する
-func renderV1(targetImage image.Image) (*image.NRGBA, error) {
-	// アセット画像の読み込み
-	base, err := loadAssetImage("assets/background/v1/base.png")
-	if err != nil {
-		return nil, err
-	}
-
-	sideMask, err := loadAssetImage("assets/background/v1/side_mask.png")
-	if err != nil {
-		return nil, err
-	}
-
-	centerMask, err := loadAssetImage("assets/background/v1/center_mask.png")
-	if err != nil {
-		return nil, err
-	}
-
-	mirrorMask, err := loadAssetImage("assets/background/v1/mirror_mask.png")
-	if err != nil {
-		return nil, err
-	}
-
-	frames, err := loadAssetImage("assets/background/v1/frames.png")
-	if err != nil {
-		return nil, err
-	}
-
-	baseBounds := base.Bounds()
-	baseSize := image.Rect(0, 0, baseBounds.Dx(), baseBounds.Dy())
-
-	// サイドジャケットの生成
-	sideJackets := imaging.New(baseBounds.Dx(), baseBounds.Dy(), image.Transparent)
-	leftNormal := morphImage(targetImage, []image.Point{{449, 114}, {1136, 99}, {465, 804}, {1152, 789}}, baseSize)
-
-	rightNormal := morphImage(targetImage, []image.Point{{1018, 92}, {1635, 51}, {1026, 756}, {1630, 740}}, baseSize)
-
-	sideJackets = imaging.Overlay(sideJackets, leftNormal, image.Point{}, 1.0)
-	sideJackets = imaging.Overlay(sideJackets, rightNormal, image.Point{}, 1.0)
-
-	// センタージャケットの生成
-	center := imaging.New(baseBounds.Dx(), baseBounds.Dy(), image.Transparent)
-	centerNormal := morphImage(targetImage, []image.Point{{798, 193}, {1252, 193}, {801, 635}, {1246, 635}}, baseSize)
-
-	centerMirror := morphImage(targetImage, []image.Point{{798, 1152}, {1252, 1152}, {795, 713}, {1252, 713}}, baseSize)
-
-	centerNormal = applyMask(centerNormal, centerMask)
-
-	centerMirror = applyMask(centerMirror, mirrorMask)
-
-	center = imaging.Overlay(center, centerNormal, image.Point{}, 1.0)
-	center = imaging.Overlay(center, centerMirror, image.Point{}, 1.0)
-
-	sideJackets = applyMask(sideJackets, sideMask)
-
-	// 最終的な合成
-	finalImage := imaging.Clone(base)
-
-	finalImage = imaging.Overlay(finalImage, sideJackets, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, center, image.Point{}, 1.0)
-
-	finalImage = imaging.Overlay(finalImage, frames, image.Point{}, 1.0)
-
-	return finalImage, nil
+	fmt.Printf("縦向き背景画像を '%s' に保存しました。\n", outputImagePath)
+	return nil
 }
 
 // loadAssetImage はアセット画像を読み込む
@@ -214,21 +77,28 @@ func loadAssetImage(assetPath string) (image.Image, error) {
 }
 
 // morphImage は画像をモーフィングする（射影変換を使用）
-func morphImage(src image.Image, targetCoords []image.Point, targetSize image.Rectangle) *image.NRGBA {
+// 指定した補間方法で色を取得し、四角形の辺は被覆率でアンチエイリアスする
+// 出力画像の行を複数のゴルーチンに分けて、Pixを直接読み書きする
+func morphImage(src image.Image, targetCoords []image.Point, targetSize image.Rectangle, filter ResampleFilter) *image.NRGBA {
 	if len(targetCoords) != 4 {
 		return imaging.New(targetSize.Dx(), targetSize.Dy(), image.Transparent)
 	}
 
-	// 元画像のサイズを取得
-	bounds := src.Bounds()
-	srcNRGBA := imaging.Clone(src)
+	srcNRGBA, ok := src.(*image.NRGBA)
+	if !ok || srcNRGBA.Rect.Min != (image.Point{}) {
+		srcNRGBA = imaging.Clone(src)
+	}
+
+	// 元画像が四角形よりかなり大きい場合は、縮小した画像から補間する
+	srcNRGBA = mipmapFor(srcNRGBA, targetCoords)
+	width, height := srcNRGBA.Rect.Dx(), srcNRGBA.Rect.Dy()
 
 	// ソース座標（元画像の四隅）
 	srcCoords := []image.Point{
-		{bounds.Min.X, bounds.Min.Y},         // 左上
-		{bounds.Max.X - 1, bounds.Min.Y},     // 右上
-		{bounds.Min.X, bounds.Max.Y - 1},     // 左下
-		{bounds.Max.X - 1, bounds.Max.Y - 1}, // 右下
+		{0, 0},                  // 左上
+		{width - 1, 0},          // 右上
+		{0, height - 1},         // 左下
+		{width - 1, height - 1}, // 右下
 	}
 
 	// 射影変換行列を計算（ターゲット → ソースの逆変換）
@@ -237,48 +107,37 @@ func morphImage(src image.Image, targetCoords []image.Point, targetSize image.Re
 	// 出力画像の作成
 	dst := imaging.New(targetSize.Dx(), targetSize.Dy(), image.Transparent)
 
-	// targetCoordsの範囲を計算
+	// targetCoordsの範囲を計算（辺のアンチエイリアスのため1ピクセル広げる）
 	minX := targetCoords[0].X
 	minY := targetCoords[0].Y
 	maxX := targetCoords[0].X
 	maxY := targetCoords[0].Y
 	for _, p := range targetCoords {
-		if p.X < minX {
-			minX = p.X
-		}
-		if p.X > maxX {
-			maxX = p.X
-		}
-		if p.Y < minY {
-			minY = p.Y
-		}
-		if p.Y > maxY {
-			maxY = p.Y
-		}
+		minX, maxX = min(minX, p.X), max(maxX, p.X)
+		minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+	}
+	minX, maxX = max(minX-1, 0), min(maxX+1, targetSize.Dx()-1)
+	minY, maxY = max(minY-1, 0), min(maxY+1, targetSize.Dy()-1)
+	if minX > maxX || minY > maxY {
+		return dst
 	}
 
-	// ターゲット領域内のピクセルのみを処理
-	for y := minY; y <= maxY; y++ {
-		if y < 0 || y >= targetSize.Dy() {
-			continue
-		}
+	kernel := filter.kernel()
+	parallelRows(minY, maxY+1, func(y int) {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Stride]
 		for x := minX; x <= maxX; x++ {
-			if x < 0 || x >= targetSize.Dx() {
+			coverage := quadCoverage(matrix, x, y, width, height)
+			if coverage == 0 {
 				continue
 			}
 
-			// 元画像の座標を計算
+			// 元画像の座標を計算（辺の外側は端の画素を使う）
 			sx, sy := applyInversePerspective(matrix, float64(x), float64(y))
-
-			// 元画像の範囲内かチェック
-			if sx >= float64(bounds.Min.X) && sx < float64(bounds.Max.X) &&
-				sy >= float64(bounds.Min.Y) && sy < float64(bounds.Max.Y) {
-				// バイリニア補間で色を取得
-				color := bilinearInterpolation(srcNRGBA, sx, sy)
-				dst.Set(x, y, color)
-			}
+			sx = clampFloat(sx, 0, float64(width-1))
+			sy = clampFloat(sy, 0, float64(height-1))
+			kernel.sample(srcNRGBA, sx, sy, coverage, row[x*4:x*4+4:x*4+4])
 		}
-	}
+	})
 
 	return dst
 }
@@ -385,41 +244,8 @@ func applyInversePerspective(m [9]float64, x, y float64) (float64, float64) {
 	return sx, sy
 }
 
-// bilinearInterpolation はバイリニア補間で色を取得する
-func bilinearInterpolation(img *image.NRGBA, x, y float64) color.Color {
-	x1 := int(math.Floor(x))
-	x2 := x1 + 1
-	y1 := int(math.Floor(y))
-	y2 := y1 + 1
-
-	if x1 < 0 || x2 >= img.Bounds().Dx() || y1 < 0 || y2 >= img.Bounds().Dy() {
-		return color.Transparent
-	}
-
-	dx := x - float64(x1)
-	dy := y - float64(y1)
-
-	c11 := img.NRGBAAt(x1, y1)
-	c12 := img.NRGBAAt(x1, y2)
-	c21 := img.NRGBAAt(x2, y1)
-	c22 := img.NRGBAAt(x2, y2)
-
-	r := bilinearValue(c11.R, c12.R, c21.R, c22.R, dx, dy)
-	g := bilinearValue(c11.G, c12.G, c21.G, c22.G, dx, dy)
-	b := bilinearValue(c11.B, c12.B, c21.B, c22.B, dx, dy)
-	a := bilinearValue(c11.A, c12.A, c21.A, c22.A, dx, dy)
-
-	return color.NRGBA{R: r, G: g, B: b, A: a}
-}
-
-// bilinearValue は指定された4つの値からバイリニア補間した値を計算する
-func bilinearValue(c11, c12, c21, c22 uint8, dx, dy float64) uint8 {
-	fx1 := float64(c11)*(1-dx)*(1-dy) + float64(c21)*dx*(1-dy)
-	fx2 := float64(c12)*(1-dx)*dy + float64(c22)*dx*dy
-	return uint8(fx1 + fx2)
-}
-
 // applyMask はマスクを適用する
+// 元画像の色はそのままに、アルファ値をマスクのアルファ値に置き換える
 func applyMask(img, mask image.Image) *image.NRGBA {
 	imgBounds := img.Bounds()
 	maskBounds := mask.Bounds()
@@ -432,23 +258,66 @@ func applyMask(img, mask image.Image) *image.NRGBA {
 		mask = imaging.Resize(mask, imgBounds.Dx(), imgBounds.Dy(), imaging.Lanczos)
 	}
 
-	// 各ピクセルに対してマスクのアルファ値を適用
-	for y := 0; y < imgBounds.Dy(); y++ {
-		for x := 0; x < imgBounds.Dx(); x++ {
-			// マスクのアルファ値を取得
+	width := imgBounds.Dx()
+	parallelRows(0, imgBounds.Dy(), func(y int) {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+		maskAlphaRow(mask, y, row)
+	})
+
+	return dst
+}
+
+// maskAlphaRow はマスクのy行目のアルファ値（8bit）をrow（NRGBA）のアルファに書き込む
+// NRGBA・RGBAのマスクはPixから直接読み、それ以外はAtで取得する
+func maskAlphaRow(mask image.Image, y int, row []uint8) {
+	width := len(row) / 4
+	var pix []uint8
+	var stride int
+	var rect image.Rectangle
+	switch m := mask.(type) {
+	case *image.NRGBA:
+		pix, stride, rect = m.Pix, m.Stride, m.Rect
+	case *image.RGBA:
+		pix, stride, rect = m.Pix, m.Stride, m.Rect
+	}
+
+	for x := 0; x < width; x++ {
+		var alpha uint8
+		if pix != nil {
+			if image.Pt(x, y).In(rect) {
+				alpha = pix[(y-rect.Min.Y)*stride+(x-rect.Min.X)*4+3]
+			}
+		} else {
 			_, _, _, maskAlpha := mask.At(x, y).RGBA()
-			maskAlpha = maskAlpha >> 8 // 16bit から 8bit に変換
-
-			// 元画像のピクセルの色情報を保持したまま、マスクのアルファ値を適用
-			c := dst.NRGBAAt(x, y)
-			dst.Set(x, y, color.NRGBA{
-				R: c.R,
-				G: c.G,
-				B: c.B,
-				A: uint8(maskAlpha),
-			})
+			alpha = uint8(maskAlpha >> 8) // 16bit から 8bit に変換
 		}
+		row[x*4+3] = alpha
 	}
+}
 
-	return dst
+// rowWorkers はparallelRowsで使うゴルーチンの最大数（テストで1にすると逐次処理になる）
+var rowWorkers = runtime.NumCPU()
+
+// parallelRows はfromからtoの手前までの各行に対してfnを呼び出す
+// 行はrowWorkersに合わせて分割し、ゴルーチンで並列に処理する
+func parallelRows(from, to int, fn func(y int)) {
+	rows := to - from
+	if rows <= 0 {
+		return
+	}
+	workers := max(min(rowWorkers, rows), 1)
+	chunk := (rows + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := from; start < to; start += chunk {
+		end := min(start+chunk, to)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for y := start; y < end; y++ {
+				fn(y)
+			}
+		}(start, end)
+	}
+	wg.Wait()
 }
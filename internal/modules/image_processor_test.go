@@ -0,0 +1,178 @@
+package modules
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// randomNRGBA はシードから決まるランダムな画像を作る
+func randomNRGBA(w, h int, seed int64) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rand.New(rand.NewSource(seed)).Read(img.Pix)
+	return img
+}
+
+// randomGray はシードから決まるランダムなグレースケール画像を作る（Atで読むマスク用）
+func randomGray(w, h int, seed int64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	rand.New(rand.NewSource(seed)).Read(img.Pix)
+	return img
+}
+
+// withRowWorkers はparallelRowsのゴルーチン数を変えてfnを実行する
+func withRowWorkers(workers int, fn func()) {
+	saved := rowWorkers
+	rowWorkers = workers
+	defer func() { rowWorkers = saved }()
+	fn()
+}
+
+// loadGolden はtestdata/imageのゴールデン画像（以前のバージョンの出力）を読み込む
+func loadGolden(t *testing.T, name string) *image.NRGBA {
+	t.Helper()
+	img, err := imaging.Open(filepath.Join("testdata", "image", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return imaging.Clone(img)
+}
+
+// testQuad は背景のジャケットのような台形
+var testQuad = []image.Point{{60, 20}, {260, 40}, {40, 180}, {280, 170}}
+
+func TestApplyMaskParallelMatchesSerial(t *testing.T) {
+	img := randomNRGBA(320, 200, 1)
+	for _, tc := range []struct {
+		name string
+		mask image.Image
+	}{
+		{"NRGBA", randomNRGBA(320, 200, 2)},
+		{"サイズ違い", randomNRGBA(160, 100, 3)},
+		{"Gray", randomGray(320, 200, 4)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var serial, parallel *image.NRGBA
+			withRowWorkers(1, func() { serial = applyMask(img, tc.mask) })
+			withRowWorkers(7, func() { parallel = applyMask(img, tc.mask) })
+			if !bytes.Equal(serial.Pix, parallel.Pix) {
+				t.Error("並列処理の結果が逐次処理と一致しません")
+			}
+		})
+	}
+}
+
+func TestMorphImageParallelMatchesSerial(t *testing.T) {
+	src := randomNRGBA(256, 256, 5)
+	size := image.Rect(0, 0, 320, 200)
+	for _, filter := range []ResampleFilter{ResampleBilinear, ResampleBicubic, ResampleLanczos} {
+		t.Run(string(filter), func(t *testing.T) {
+			var serial, parallel *image.NRGBA
+			withRowWorkers(1, func() { serial = morphImage(src, testQuad, size, filter) })
+			withRowWorkers(7, func() { parallel = morphImage(src, testQuad, size, filter) })
+			if !bytes.Equal(serial.Pix, parallel.Pix) {
+				t.Error("並列処理の結果が逐次処理と一致しません")
+			}
+		})
+	}
+}
+
+// TestApplyMaskGolden はマスクの適用結果が以前のバージョン（逐次処理）の出力と一致するかを確認する
+func TestApplyMaskGolden(t *testing.T) {
+	img := randomNRGBA(64, 40, 11)
+	for _, tc := range []struct {
+		golden string
+		mask   image.Image
+	}{
+		{"mask_nrgba.png", randomNRGBA(64, 40, 12)},
+		{"mask_resized.png", randomNRGBA(32, 20, 13)},
+		{"mask_gray.png", randomGray(64, 40, 14)},
+	} {
+		for _, workers := range []int{1, 7} {
+			var got *image.NRGBA
+			withRowWorkers(workers, func() { got = applyMask(img, tc.mask) })
+			if !bytes.Equal(got.Pix, loadGolden(t, tc.golden).Pix) {
+				t.Errorf("%s (%dスレッド): 以前のバージョンの出力と一致しません", tc.golden, workers)
+			}
+		}
+	}
+}
+
+// TestMorphImageGolden は四角形の内側の画素が以前のバージョンの出力と一致するかを確認する
+// 辺のアンチエイリアスと四捨五入による±1の差は許容する
+func TestMorphImageGolden(t *testing.T) {
+	src := randomNRGBA(48, 48, 15)
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 255
+	}
+	quad := []image.Point{{10, 6}, {70, 10}, {6, 52}, {74, 50}}
+	want := loadGolden(t, "morph.png")
+	matrix := calculatePerspectiveMatrix(quad, []image.Point{{0, 0}, {47, 0}, {0, 47}, {47, 47}})
+
+	var got *image.NRGBA
+	withRowWorkers(7, func() { got = morphImage(src, quad, want.Rect, ResampleBilinear) })
+	checked := 0
+	for y := 0; y < want.Rect.Dy(); y++ {
+		for x := 0; x < want.Rect.Dx(); x++ {
+			if quadCoverage(matrix, x, y, 48, 48) < 1 {
+				continue
+			}
+			checked++
+			g, w := got.NRGBAAt(x, y), want.NRGBAAt(x, y)
+			for i, d := range []int{int(g.R) - int(w.R), int(g.G) - int(w.G), int(g.B) - int(w.B), int(g.A) - int(w.A)} {
+				if d < -1 || d > 1 {
+					t.Fatalf("(%d, %d) の%d番目の値: got %v, want %v", x, y, i, g, w)
+				}
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("比較した画素がありません")
+	}
+}
+
+// benchWorkers はベンチマークで比較する逐次処理（rowWorkers=1）と並列処理のゴルーチン数
+var benchWorkers = []struct {
+	name    string
+	workers int
+}{
+	{"serial", 1},
+	{fmt.Sprintf("parallel%d", runtime.NumCPU()), runtime.NumCPU()},
+}
+
+func BenchmarkApplyMask(b *testing.B) {
+	img := randomNRGBA(1920, 1080, 1)
+	mask := randomNRGBA(1920, 1080, 2)
+	for _, bc := range benchWorkers {
+		b.Run(bc.name, func(b *testing.B) {
+			withRowWorkers(bc.workers, func() {
+				for i := 0; i < b.N; i++ {
+					applyMask(img, mask)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkMorphImage(b *testing.B) {
+	src := randomNRGBA(740, 740, 1)
+	quad := []image.Point{{449, 114}, {1438, 70}, {269, 1049}, {1633, 1013}}
+	size := image.Rect(0, 0, 1920, 1080)
+	for _, filter := range []ResampleFilter{ResampleBilinear, ResampleBicubic, ResampleLanczos} {
+		for _, bc := range benchWorkers {
+			b.Run(string(filter)+"/"+bc.name, func(b *testing.B) {
+				withRowWorkers(bc.workers, func() {
+					for i := 0; i < b.N; i++ {
+						morphImage(src, quad, size, filter)
+					}
+				})
+			})
+		}
+	}
+}
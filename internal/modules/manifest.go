@@ -0,0 +1,61 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestFile は出力フォルダに書き出す生成情報のファイル名
+const manifestFile = "manifest.json"
+
+// 素材の取得元
+const (
+	SourceServer = "server"
+	SourceLocal  = "local"
+)
+
+// ResourceSource は素材の取得元と、出力フォルダ内のファイル名
+type ResourceSource struct {
+	// Origin はserver（ダウンロード）またはlocal（ローカルのファイルで置き換え）
+	Origin string `json:"origin"`
+	// Location はダウンロードしたURLまたはローカルのファイルのパス
+	Location string `json:"location"`
+	File     string `json:"file"`
+}
+
+// ManifestBackground は背景画像の取得方法
+type ManifestBackground struct {
+	Source string `json:"source"`
+	Layout string `json:"layout,omitempty"`
+	Image  string `json:"image,omitempty"`
+}
+
+// OutputManifest は出力フォルダの生成情報（manifest.json）
+type OutputManifest struct {
+	LevelID     string             `json:"level_id"`
+	AppVersion  string             `json:"app_version"`
+	GeneratedAt string             `json:"generated_at"`
+	Jacket      ResourceSource     `json:"jacket"`
+	Audio       ResourceSource     `json:"audio"`
+	Background  ManifestBackground `json:"background"`
+}
+
+// WriteManifest はmanifest.jsonを出力する（生成日時は現在時刻で上書きする）
+func WriteManifest(distDir string, manifest OutputManifest) error {
+	manifest.GeneratedAt = time.Now().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return fmt.Errorf("manifest.jsonの作成に失敗しました: %w", err)
+	}
+
+	outputPath := filepath.Join(distDir, manifestFile)
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("manifest.jsonの書き込みに失敗しました: %w", err)
+	}
+	fmt.Printf("生成情報を '%s' に保存しました。\n", outputPath)
+	return nil
+}
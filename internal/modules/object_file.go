@@ -0,0 +1,413 @@
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ObjectFile はAviUtl2の.objectファイル（エイリアス）を表す構造体
+type ObjectFile struct {
+	Objects []*TimelineObject
+}
+
+// TimelineObject はタイムライン上の1つのオブジェクト（[n]セクション）を表す構造体
+type TimelineObject struct {
+	Layer   int
+	Frames  []int
+	Props   []ObjectParam
+	Effects []*ObjectEffect
+}
+
+// ObjectEffect はオブジェクトに適用されるフィルタ効果（[n.m]セクション）を表す構造体
+type ObjectEffect struct {
+	Name   string
+	Params []ObjectParam
+}
+
+// ObjectParam はキーと値の組を表す構造体（ファイル内の順序を保持する）
+type ObjectParam struct {
+	Key   string
+	Value string
+}
+
+// AnimatedValue はトラックバーの値を表す構造体
+// 例: "100.00" や "100.00,0.00,0.00,直線移動,0" や "42.50,0.00,補間移動(時間制御),0|0.18,0.50,0.53,0.07"
+type AnimatedValue struct {
+	Values     []float64
+	Precisions []int
+	Method     string
+	Param      string
+}
+
+var objectHeaderPattern = regexp.MustCompile(`^\[(\d+)(?:\.(\d+))?\]$`)
+
+// ParseObjectFile は.objectファイルの内容を解析する
+// オブジェクトの番号は省略があってもよいが昇順である必要があり、フィルタ効果の番号は0からの連番である必要がある
+func ParseObjectFile(content string) (*ObjectFile, error) {
+	file := &ObjectFile{}
+	var currentObject *TimelineObject
+	var currentEffect *ObjectEffect
+	objectIndex := -1
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if m := objectHeaderPattern.FindStringSubmatch(line); m != nil {
+			index, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("%d行目: 無効なセクション番号です: %s", lineNo, line)
+			}
+			if m[2] == "" {
+				if index <= objectIndex {
+					return nil, fmt.Errorf("%d行目: オブジェクトの番号が昇順ではありません: %s", lineNo, line)
+				}
+				objectIndex = index
+				currentObject = &TimelineObject{Layer: -1}
+				currentEffect = nil
+				file.Objects = append(file.Objects, currentObject)
+				continue
+			}
+			if currentObject == nil {
+				return nil, fmt.Errorf("%d行目: オブジェクトの前にフィルタ効果のセクションがあります", lineNo)
+			}
+			effectIndex, err := strconv.Atoi(m[2])
+			if err != nil || index != objectIndex || effectIndex != len(currentObject.Effects) {
+				return nil, fmt.Errorf("%d行目: フィルタ効果のセクション番号が一致しません (%s, 期待値: [%d.%d])", lineNo, line, objectIndex, len(currentObject.Effects))
+			}
+			currentEffect = &ObjectEffect{}
+			currentObject.Effects = append(currentObject.Effects, currentEffect)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d行目: 解析できない行です: %s", lineNo, line)
+		}
+		if currentObject == nil {
+			return nil, fmt.Errorf("%d行目: セクションの外に値があります: %s", lineNo, line)
+		}
+
+		if currentEffect != nil {
+			if key == "effect.name" {
+				currentEffect.Name = value
+			} else {
+				currentEffect.Params = append(currentEffect.Params, ObjectParam{Key: key, Value: value})
+			}
+			continue
+		}
+
+		switch key {
+		case "layer":
+			layer, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%d行目: 無効なレイヤー番号です: %s", lineNo, value)
+			}
+			currentObject.Layer = layer
+		case "frame":
+			frames, err := parseFrameList(value)
+			if err != nil {
+				return nil, fmt.Errorf("%d行目: %w", lineNo, err)
+			}
+			currentObject.Frames = frames
+		default:
+			currentObject.Props = append(currentObject.Props, ObjectParam{Key: key, Value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("オブジェクトファイルの読み込みに失敗しました: %w", err)
+	}
+
+	return file, nil
+}
+
+// parseFrameList は "frame=" の値（開始,中間点...,終了）を解析する
+func parseFrameList(value string) ([]int, error) {
+	parts := strings.Split(value, ",")
+	frames := make([]int, 0, len(parts))
+	for _, part := range parts {
+		frame, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("無効なフレーム番号です: %s", value)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// String はObjectFileを.objectファイル形式に変換する（セクション番号は0から振り直す）
+func (f *ObjectFile) String() string {
+	var b strings.Builder
+	for i, obj := range f.Objects {
+		fmt.Fprintf(&b, "[%d]\n", i)
+		fmt.Fprintf(&b, "layer=%d\n", obj.Layer)
+
+		frames := make([]string, len(obj.Frames))
+		for j, frame := range obj.Frames {
+			frames[j] = strconv.Itoa(frame)
+		}
+		fmt.Fprintf(&b, "frame=%s\n", strings.Join(frames, ","))
+
+		for _, prop := range obj.Props {
+			fmt.Fprintf(&b, "%s=%s\n", prop.Key, prop.Value)
+		}
+		for j, effect := range obj.Effects {
+			fmt.Fprintf(&b, "[%d.%d]\n", i, j)
+			fmt.Fprintf(&b, "effect.name=%s\n", effect.Name)
+			for _, param := range effect.Params {
+				fmt.Fprintf(&b, "%s=%s\n", param.Key, param.Value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Validate はオブジェクトファイルが正しい形式かどうかを検証する
+func (f *ObjectFile) Validate() error {
+	type span struct {
+		start, end, index int
+	}
+	layers := map[int][]span{}
+
+	for i, obj := range f.Objects {
+		if obj.Layer < 0 {
+			return fmt.Errorf("オブジェクト[%d]: レイヤー番号がありません", i)
+		}
+		if len(obj.Frames) < 2 {
+			return fmt.Errorf("オブジェクト[%d]: フレーム範囲がありません", i)
+		}
+		for j := 1; j < len(obj.Frames); j++ {
+			if obj.Frames[j] < obj.Frames[j-1] {
+				return fmt.Errorf("オブジェクト[%d]: フレーム範囲が逆順です (%v)", i, obj.Frames)
+			}
+		}
+		if obj.Start() < 0 {
+			return fmt.Errorf("オブジェクト[%d]: 開始フレームが負の値です (%d)", i, obj.Start())
+		}
+		if len(obj.Effects) == 0 {
+			return fmt.Errorf("オブジェクト[%d]: フィルタ効果がありません", i)
+		}
+		for j, effect := range obj.Effects {
+			if effect.Name == "" {
+				return fmt.Errorf("オブジェクト[%d.%d]: effect.nameがありません", i, j)
+			}
+		}
+		layers[obj.Layer] = append(layers[obj.Layer], span{obj.Start(), obj.End(), i})
+	}
+
+	// 同じレイヤー上でオブジェクトが重なっていないかを確認
+	for layer, spans := range layers {
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].start < spans[j].start
+		})
+		for i := 1; i < len(spans); i++ {
+			if spans[i].start <= spans[i-1].end {
+				return fmt.Errorf("レイヤー%d: オブジェクト[%d]と[%d]のフレームが重なっています", layer, spans[i-1].index, spans[i].index)
+			}
+		}
+	}
+	return nil
+}
+
+// FindByEffect は指定したフィルタ効果を持つオブジェクトを返す
+func (f *ObjectFile) FindByEffect(name string) []*TimelineObject {
+	var result []*TimelineObject
+	for _, obj := range f.Objects {
+		if obj.Effect(name) != nil {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// RemoveObjects は条件に一致するオブジェクトを削除し、削除した数を返す
+func (f *ObjectFile) RemoveObjects(match func(*TimelineObject) bool) int {
+	kept := f.Objects[:0]
+	removed := 0
+	for _, obj := range f.Objects {
+		if match(obj) {
+			removed++
+			continue
+		}
+		kept = append(kept, obj)
+	}
+	f.Objects = kept
+	return removed
+}
+
+// EndFrame はオブジェクト全体の最終フレームを返す
+func (f *ObjectFile) EndFrame() int {
+	end := 0
+	for _, obj := range f.Objects {
+		if obj.End() > end {
+			end = obj.End()
+		}
+	}
+	return end
+}
+
+// Start はオブジェクトの開始フレームを返す
+func (o *TimelineObject) Start() int {
+	if len(o.Frames) == 0 {
+		return 0
+	}
+	return o.Frames[0]
+}
+
+// End はオブジェクトの終了フレームを返す
+func (o *TimelineObject) End() int {
+	if len(o.Frames) == 0 {
+		return 0
+	}
+	return o.Frames[len(o.Frames)-1]
+}
+
+// Prop はオブジェクトのプロパティ（group, camera など）を取得する
+func (o *TimelineObject) Prop(key string) (string, bool) {
+	return getParam(o.Props, key)
+}
+
+// Effect は指定した名前のフィルタ効果を返す
+func (o *TimelineObject) Effect(name string) *ObjectEffect {
+	for _, effect := range o.Effects {
+		if effect.Name == name {
+			return effect
+		}
+	}
+	return nil
+}
+
+// SetPosition は標準描画（映像再生・グループ制御）のX,Y座標を変更する
+// アニメーションしている場合は全ての値を同じだけ移動させる
+func (o *TimelineObject) SetPosition(x, y float64) error {
+	for _, name := range []string{"標準描画", "映像再生", "グループ制御"} {
+		effect := o.Effect(name)
+		if effect == nil {
+			continue
+		}
+		for key, target := range map[string]float64{"X": x, "Y": y} {
+			value, err := effect.Animated(key)
+			if err != nil {
+				return err
+			}
+			delta := target - value.Values[0]
+			for i := range value.Values {
+				value.Values[i] += delta
+			}
+			effect.Set(key, value.String())
+		}
+		return nil
+	}
+	return fmt.Errorf("座標を持つフィルタ効果がありません")
+}
+
+// Disabled はフィルタ効果が無効化されているかを返す
+func (e *ObjectEffect) Disabled() bool {
+	value, _ := e.Get("effect.disable")
+	return value == "1"
+}
+
+// Get はパラメータの値を取得する
+func (e *ObjectEffect) Get(key string) (string, bool) {
+	return getParam(e.Params, key)
+}
+
+// Set はパラメータの値を設定する（存在しない場合は追加する）
+func (e *ObjectEffect) Set(key, value string) {
+	for i := range e.Params {
+		if e.Params[i].Key == key {
+			e.Params[i].Value = value
+			return
+		}
+	}
+	e.Params = append(e.Params, ObjectParam{Key: key, Value: value})
+}
+
+// Animated はトラックバーのパラメータをAnimatedValueとして取得する
+func (e *ObjectEffect) Animated(key string) (AnimatedValue, error) {
+	value, ok := e.Get(key)
+	if !ok {
+		return AnimatedValue{}, fmt.Errorf("%s: パラメータ '%s' がありません", e.Name, key)
+	}
+	return ParseAnimatedValue(value)
+}
+
+// ParseAnimatedValue はトラックバーの値（固定値またはアニメーション）を解析する
+func ParseAnimatedValue(value string) (AnimatedValue, error) {
+	parts := strings.Split(value, ",")
+	result := AnimatedValue{}
+
+	i := 0
+	for ; i < len(parts); i++ {
+		v, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			break
+		}
+		result.Values = append(result.Values, v)
+		result.Precisions = append(result.Precisions, decimalPlaces(parts[i]))
+	}
+	if len(result.Values) == 0 {
+		return AnimatedValue{}, fmt.Errorf("数値ではない値です: %s", value)
+	}
+	if i < len(parts) {
+		result.Method = parts[i]
+		// 移動方法の設定値には "|" 区切りでカンマを含む値が続くことがある
+		result.Param = strings.Join(parts[i+1:], ",")
+	}
+	return result, nil
+}
+
+// decimalPlaces は数値文字列の小数点以下の桁数を返す
+func decimalPlaces(s string) int {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// IsAnimated は値がアニメーションしているかを返す
+func (v AnimatedValue) IsAnimated() bool {
+	return v.Method != ""
+}
+
+// String はAnimatedValueを.objectファイルの値に変換する
+func (v AnimatedValue) String() string {
+	parts := make([]string, 0, len(v.Values)+2)
+	for i, value := range v.Values {
+		// 元の桁数を保持する（追加された値は先頭の値の桁数に合わせる）
+		precision := 2
+		if i < len(v.Precisions) {
+			precision = v.Precisions[i]
+		} else if len(v.Precisions) > 0 {
+			precision = v.Precisions[0]
+		}
+		parts = append(parts, strconv.FormatFloat(value, 'f', precision, 64))
+	}
+	if v.Method != "" {
+		parts = append(parts, v.Method)
+		if v.Param != "" {
+			parts = append(parts, v.Param)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// getParam はパラメータリストから値を取得する
+func getParam(params []ObjectParam, key string) (string, bool) {
+	for _, param := range params {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
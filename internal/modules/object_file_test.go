@@ -0,0 +1,226 @@
+package modules
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// sectionNumberPattern はセクション番号（[3]、[3.0]など）にマッチする
+var sectionNumberPattern = regexp.MustCompile(`(?m)^\[\d+(\.\d+)?\]$`)
+
+// testAliasData はテンプレートの展開に使う値（GenerateAliasObjectと同じキー）
+func testAliasData(format VideoFormat) map[string]interface{} {
+	return map[string]interface{}{
+		"levelID":         "chcy-test",
+		"title":           "テスト曲",
+		"author":          "作者",
+		"words":           "作詞者",
+		"music":           "作曲者",
+		"arrange":         "編曲者",
+		"vocal":           "ボーカル",
+		"difficulty":      "MASTER",
+		"difficulty_img":  "master",
+		"rating":          30.0,
+		"distPath":        `C:\dist\chcy-test`,
+		"assetsPath":      `C:\sekai-overlay\assets`,
+		"jacketFile":      "jacket.jpg",
+		"musicFile":       "music.mp3",
+		"fps":             format.FPS,
+		"width":           format.Width,
+		"height":          format.Height,
+		"layout":          format.Layout,
+		"backgroundZoom":  0.0,
+		"lastNoteTime":    120.5,
+		"clipStart":       0.0,
+		"clipEnd":         120.5,
+		"musicStart":      "0.000",
+		"scoreOffset":     format.Frame(1),
+		"hudStartFrame":   format.Frames(startScreenBaseFrames),
+		"musicStartFrame": format.Frames(startScreenBaseFrames) + format.Frame(1),
+		"endScreen":       true,
+		"startScreenText": false,
+		"videoStartFrame": 8000,
+		"fadeStartFrame":  8100,
+		"fadeStopFrame":   8200,
+		"endFrame":        8300,
+	}
+}
+
+func TestObjectFileRoundTrip(t *testing.T) {
+	tmpl := AliasTemplate{Name: DefaultAliasTemplate, Path: filepath.Join("..", "..", "assets", "alias", "template.object")}
+
+	for _, tc := range []struct {
+		name   string
+		layout string
+		fps    int
+		modify func(data map[string]interface{})
+		// renumbered はオブジェクトが省略されてセクション番号が振り直される場合
+		renumbered bool
+	}{
+		{"landscape", LayoutLandscape, 60, nil, false},
+		{"portrait", LayoutPortrait, 30, nil, false},
+		{"start_text", LayoutLandscape, 60, func(data map[string]interface{}) { data["startScreenText"] = true }, false},
+		{"no_vocal", LayoutLandscape, 120, func(data map[string]interface{}) { data["vocal"] = "" }, false},
+		{"no_end_screen", LayoutPortrait, 60, func(data map[string]interface{}) { data["endScreen"] = false }, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := NewVideoFormat(tc.fps, "1080p", tc.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := testAliasData(format)
+			if tc.modify != nil {
+				tc.modify(data)
+			}
+
+			rendered, err := executeAliasTemplate(tmpl, data, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			objectFile, err := ParseObjectFile(rendered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := objectFile.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			written := objectFile.String()
+			if tc.renumbered {
+				// セクション番号以外が一致し、もう一度解析・書き出しても変わらないことを確認する
+				reparsed, err := ParseObjectFile(written)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reparsed.String() != written {
+					t.Fatal("書き出した.objectを解析・書き出すと内容が変わります")
+				}
+				written = sectionNumberPattern.ReplaceAllString(written, "[]")
+				rendered = sectionNumberPattern.ReplaceAllString(rendered, "[]")
+			}
+			if written == rendered {
+				return
+			}
+			got, want := strings.Split(written, "\n"), strings.Split(rendered, "\n")
+			for i := 0; i < len(got) && i < len(want); i++ {
+				if got[i] != want[i] {
+					t.Fatalf("%d行目が一致しません\ngot:  %q\nwant: %q", i+1, got[i], want[i])
+				}
+			}
+			t.Fatalf("行数が一致しません (got: %d, want: %d)", len(got), len(want))
+		})
+	}
+}
+
+func TestParseObjectFileSectionNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"連番", "[0]\nlayer=1\n[0.0]\neffect.name=A\n[0.1]\neffect.name=B\n[1]\nlayer=2\n[1.0]\neffect.name=A\n", true},
+		{"オブジェクトの省略", "[0]\nlayer=1\n[0.0]\neffect.name=A\n[3]\nlayer=2\n[3.0]\neffect.name=A\n", true},
+		{"オブジェクトが逆順", "[1]\nlayer=1\n[1.0]\neffect.name=A\n[0]\nlayer=2\n", false},
+		{"オブジェクトの重複", "[0]\nlayer=1\n[0]\nlayer=2\n", false},
+		{"別のオブジェクトのフィルタ効果", "[0]\nlayer=1\n[0.0]\neffect.name=A\n[1]\nlayer=2\n[0.1]\neffect.name=B\n", false},
+		{"フィルタ効果の番号飛び", "[0]\nlayer=1\n[0.0]\neffect.name=A\n[0.2]\neffect.name=B\n", false},
+		{"フィルタ効果が1から", "[0]\nlayer=1\n[0.1]\neffect.name=A\n", false},
+	} {
+		_, err := ParseObjectFile(tc.content)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+func TestAnimatedValue(t *testing.T) {
+	for _, tc := range []struct {
+		input      string
+		values     []float64
+		method     string
+		param      string
+		isAnimated bool
+	}{
+		{"100.00", []float64{100}, "", "", false},
+		{"-584.840", []float64{-584.84}, "", "", false},
+		{"100.00,0.00,0.00,直線移動,0", []float64{100, 0, 0}, "直線移動", "0", true},
+		{"42.50,0.00,補間移動(時間制御),0|0.18,0.50,0.53,0.07", []float64{42.5, 0}, "補間移動(時間制御)", "0|0.18,0.50,0.53,0.07", true},
+		{"1,2,瞬間移動", []float64{1, 2}, "瞬間移動", "", true},
+	} {
+		value, err := ParseAnimatedValue(tc.input)
+		if err != nil {
+			t.Errorf("%s: %v", tc.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(value.Values, tc.values) || value.Method != tc.method || value.Param != tc.param || value.IsAnimated() != tc.isAnimated {
+			t.Errorf("ParseAnimatedValue(%q) = %+v", tc.input, value)
+		}
+		// 桁数を含めて元の文字列に戻る
+		if got := value.String(); got != tc.input {
+			t.Errorf("String() = %q, want %q", got, tc.input)
+		}
+	}
+
+	for _, input := range []string{"", "直線移動", "X=42.50"} {
+		if _, err := ParseAnimatedValue(input); err == nil {
+			t.Errorf("ParseAnimatedValue(%q) がエラーになりません", input)
+		}
+	}
+}
+
+func TestSetPosition(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		effect *ObjectEffect
+		wantX  string
+		wantY  string
+	}{
+		{
+			"映像再生",
+			&ObjectEffect{Name: "映像再生", Params: []ObjectParam{{"X", "10.00"}, {"Y", "-5.00"}}},
+			"100.00", "200.00",
+		},
+		{
+			"グループ制御のアニメーション",
+			&ObjectEffect{Name: "グループ制御", Params: []ObjectParam{{"X", "10.00,30.00,直線移動,0"}, {"Y", "0.0,-20.0,0.0,加減速移動,1"}}},
+			"100.00,120.00,直線移動,0", "200.0,180.0,200.0,加減速移動,1",
+		},
+	} {
+		obj := &TimelineObject{Effects: []*ObjectEffect{{Name: "画像ファイル"}, tc.effect}}
+		if err := obj.SetPosition(100, 200); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		x, _ := tc.effect.Get("X")
+		y, _ := tc.effect.Get("Y")
+		if x != tc.wantX || y != tc.wantY {
+			t.Errorf("%s: X = %q, Y = %q, want %q, %q", tc.name, x, y, tc.wantX, tc.wantY)
+		}
+	}
+
+	obj := &TimelineObject{Effects: []*ObjectEffect{{Name: "画像ファイル"}}}
+	if err := obj.SetPosition(0, 0); err == nil {
+		t.Error("座標を持たないオブジェクトでエラーになりません")
+	}
+}
+
+func TestRemoveObjectsRenumbers(t *testing.T) {
+	content := "[0]\nlayer=1\nframe=0,9\n[0.0]\neffect.name=A\n" +
+		"[1]\nlayer=2\nframe=0,9\n[1.0]\neffect.name=B\n" +
+		"[2]\nlayer=3\nframe=0,9\n[2.0]\neffect.name=C\n[2.1]\neffect.name=標準描画\n"
+	file, err := ParseObjectFile(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := file.RemoveObjects(func(obj *TimelineObject) bool { return obj.Effect("B") != nil })
+	if removed != 1 {
+		t.Fatalf("削除した数 = %d, want 1", removed)
+	}
+	want := "[0]\nlayer=1\nframe=0,9\n[0.0]\neffect.name=A\n" +
+		"[1]\nlayer=3\nframe=0,9\n[1.0]\neffect.name=C\n[1.1]\neffect.name=標準描画\n"
+	if got := file.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,640 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// overlayDirName はHUDの連番画像の出力先ディレクトリ名
+const overlayDirName = "overlay"
+
+// SekaiObjectsのフィルタ効果名
+const (
+	effectInitSettings = "InitSettings@SekaiObjects"
+	effectCombo        = "Combo@SekaiObjects"
+	effectScore        = "Score@SekaiObjects"
+	effectLife         = "Life@SekaiObjects"
+	effectJudgement    = "Judgement@SekaiObjects"
+)
+
+// judgeImages はJudgementのJudgeの値に対応する画像名
+var judgeImages = []string{"perfect", "great", "good", "bad", "miss", "auto"}
+
+// OverlayOptions はHUDの連番画像出力のオプション
+type OverlayOptions struct {
+	Format VideoFormat
+	// Workers は並列で描画するフレーム数（0の場合はCPU数）
+	Workers int
+}
+
+// hudLayer はmain.objectのSekaiObjectsのオブジェクト1つ分の描画設定
+type hudLayer struct {
+	effect  *ObjectEffect
+	object  *TimelineObject
+	x, y    float64
+	scale   float64
+	opacity float64
+}
+
+// overlayRenderer は@SekaiObjects.obj2と同じ規則でHUDを描画する
+type overlayRenderer struct {
+	format VideoFormat
+	frames []ScoreFrame
+	init   *TimelineObject
+	offset float64
+	layers []hudLayer
+	cache  *hudImageCache
+}
+
+// RenderOverlaySequence はmain.objectとskobj_data.jsonからHUD（コンボ・スコア・ライフ・判定）を描画し、
+// タイムラインのフレーム番号を付けた透過PNGとして出力する
+// 座標・拡大率・トラックバーの値はmain.objectのSekaiObjectsのオブジェクトから取得する
+func RenderOverlaySequence(distDir string, opts OverlayOptions) error {
+	fmt.Println("HUDの連番画像の生成を開始します...")
+
+	objectData, err := os.ReadFile(filepath.Join(distDir, "main.object"))
+	if err != nil {
+		return fmt.Errorf("main.objectの読み込みに失敗しました: %w", err)
+	}
+	objectFile, err := ParseObjectFile(string(objectData))
+	if err != nil {
+		return err
+	}
+
+	skobjData, err := os.ReadFile(filepath.Join(distDir, "skobj_data.json"))
+	if err != nil {
+		return fmt.Errorf("skobj_data.jsonの読み込みに失敗しました: %w", err)
+	}
+	var skobj SkobjData
+	if err := json.Unmarshal(skobjData, &skobj); err != nil {
+		return fmt.Errorf("skobj_data.jsonの解析に失敗しました: %w", err)
+	}
+
+	renderer, err := newOverlayRenderer(objectFile, skobj.Objects, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	outputDir := filepath.Join(distDir, overlayDirName)
+	totalFrames := objectFile.EndFrame() + 1
+	render := func(frame int) image.Image {
+		if canvas := renderer.renderFrame(frame); canvas != nil {
+			return canvas
+		}
+		return nil
+	}
+	if err := writeFrameSequence(outputDir, totalFrames, opts.Format, opts.Workers, render); err != nil {
+		return err
+	}
+
+	fmt.Printf("HUDの連番画像を '%s' に保存しました。(%s, %dフレーム)\n", outputDir, opts.Format, totalFrames)
+	return nil
+}
+
+// writeFrameSequence は各フレームを描画して、フレーム番号を付けたPNGとして並列に書き出す
+// renderがnilを返したフレームは同じ透過画像を書き出す
+func writeFrameSequence(outputDir string, totalFrames int, format VideoFormat, workers int, render func(frame int) image.Image) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+	oldFiles, _ := filepath.Glob(filepath.Join(outputDir, "*.png"))
+	for _, path := range oldFiles {
+		os.Remove(path)
+	}
+
+	var empty bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&empty, image.NewNRGBA(image.Rect(0, 0, format.Width, format.Height))); err != nil {
+		return fmt.Errorf("PNGのエンコードに失敗しました: %w", err)
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for frame := range jobs {
+				path := filepath.Join(outputDir, fmt.Sprintf("%06d.png", frame))
+				canvas := render(frame)
+				if canvas == nil {
+					if err := os.WriteFile(path, empty.Bytes(), 0644); err != nil {
+						errs <- fmt.Errorf("連番画像の書き込みに失敗しました: %w", err)
+						return
+					}
+					continue
+				}
+				file, err := os.Create(path)
+				if err != nil {
+					errs <- fmt.Errorf("連番画像の作成に失敗しました: %w", err)
+					return
+				}
+				err = encoder.Encode(file, canvas)
+				file.Close()
+				if err != nil {
+					errs <- fmt.Errorf("PNGのエンコードに失敗しました: %w", err)
+					return
+				}
+			}
+		}()
+	}
+
+	var renderErr error
+	for frame := 0; frame < totalFrames && renderErr == nil; frame++ {
+		select {
+		case jobs <- frame:
+		case renderErr = <-errs:
+		}
+		if (frame+1)%500 == 0 {
+			fmt.Printf("  -> %d / %d フレーム\n", frame+1, totalFrames)
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if renderErr == nil {
+		select {
+		case renderErr = <-errs:
+		default:
+		}
+	}
+	return renderErr
+}
+
+// newOverlayRenderer はmain.objectからSekaiObjectsのオブジェクトを取得して描画の準備をする
+func newOverlayRenderer(objectFile *ObjectFile, frames []ScoreFrame, format VideoFormat) (*overlayRenderer, error) {
+	inits := objectFile.FindByEffect(effectInitSettings)
+	if len(inits) == 0 {
+		return nil, fmt.Errorf("main.objectに%sが見つかりません", effectInitSettings)
+	}
+
+	sorted := append([]ScoreFrame(nil), frames...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seconds < sorted[j].Seconds })
+
+	r := &overlayRenderer{
+		format: format,
+		frames: sorted,
+		init:   inits[0],
+		offset: effectFloat(inits[0].Effect(effectInitSettings), "offset", 0),
+		cache:  newHUDImageCache(),
+	}
+
+	for _, obj := range objectFile.Objects {
+		for _, name := range []string{effectCombo, effectScore, effectLife, effectJudgement} {
+			effect := obj.Effect(name)
+			if effect == nil || effect.Disabled() {
+				continue
+			}
+			draw := obj.Effect("標準描画")
+			r.layers = append(r.layers, hudLayer{
+				effect:  effect,
+				object:  obj,
+				x:       effectFloat(draw, "X", 0),
+				y:       effectFloat(draw, "Y", 0),
+				scale:   effectFloat(draw, "拡大率", 100) / 100,
+				opacity: 1 - effectFloat(draw, "透明度", 0)/100,
+			})
+		}
+	}
+	if len(r.layers) == 0 {
+		return nil, fmt.Errorf("main.objectにHUDのオブジェクトが見つかりません")
+	}
+
+	// 下のレイヤーから順に重ねる
+	sort.SliceStable(r.layers, func(i, j int) bool { return r.layers[i].object.Layer < r.layers[j].object.Layer })
+	return r, nil
+}
+
+// effectFloat はフィルタ効果のパラメータを数値で取得する（アニメーションしている場合は開始値）
+func effectFloat(effect *ObjectEffect, key string, defaultValue float64) float64 {
+	if effect == nil {
+		return defaultValue
+	}
+	value, err := effect.Animated(key)
+	if err != nil || len(value.Values) == 0 {
+		return defaultValue
+	}
+	return value.Values[0]
+}
+
+// currentScoreFrame はInitSettingsと同様に、指定フレームで表示するスコアデータを返す
+func (r *overlayRenderer) currentScoreFrame(relativeFrame float64) ScoreFrame {
+	fps := float64(r.format.FPS)
+	index := sort.Search(len(r.frames), func(i int) bool {
+		return r.frames[i].Seconds*fps >= relativeFrame
+	})
+	if index == 0 {
+		return ScoreFrame{Rank: "none"}
+	}
+	return r.frames[index-1]
+}
+
+// renderFrame は指定フレームのHUDを描画する（何も表示されない場合はnilを返す）
+func (r *overlayRenderer) renderFrame(frame int) *image.NRGBA {
+	if frame < r.init.Start() || frame > r.init.End() {
+		return nil
+	}
+	current := r.currentScoreFrame(float64(frame-r.init.Start()) - r.offset)
+
+	var canvas *image.NRGBA
+	for _, layer := range r.layers {
+		if frame < layer.object.Start() || frame > layer.object.End() {
+			continue
+		}
+		objectFrame := float64(frame - layer.object.Start())
+		progress := objectFrame - r.offset - current.Seconds*float64(r.format.FPS)
+
+		var buffer *hudBuffer
+		switch layer.effect.Name {
+		case effectCombo:
+			buffer = r.renderCombo(layer, current, progress, objectFrame/float64(r.format.FPS))
+		case effectScore:
+			buffer = r.renderScore(layer, current, progress)
+		case effectLife:
+			buffer = r.renderLife(layer)
+		case effectJudgement:
+			buffer = r.renderJudgement(layer, current, progress)
+		}
+		if buffer == nil {
+			continue
+		}
+
+		if canvas == nil {
+			canvas = image.NewNRGBA(image.Rect(0, 0, r.format.Width, r.format.Height))
+		}
+		bounds := buffer.img.Bounds()
+		pos := image.Pt(
+			int(math.Round(float64(r.format.Width)/2+layer.x-float64(bounds.Dx())/2)),
+			int(math.Round(float64(r.format.Height)/2+layer.y-float64(bounds.Dy())/2)),
+		)
+		overlayNRGBA(canvas, buffer.img, pos, layer.opacity)
+	}
+	return canvas
+}
+
+// renderCombo は@Comboと同じ規則でコンボ数を描画する
+func (r *overlayRenderer) renderCombo(layer hudLayer, current ScoreFrame, progress, seconds float64) *hudBuffer {
+	if current.Combo <= 0 {
+		return nil
+	}
+	ap := effectFloat(layer.effect, "AP", 1) == 1
+	xAreaExpand := effectFloat(layer.effect, "X Area Expand", 1000)
+
+	combo := strconv.Itoa(current.Combo)
+	ofs := float64(len(combo)-1) * -51
+	auraAlpha := (math.Sin(seconds*4) + 1) / 2
+	buffer := r.newBuffer(500+xAreaExpand, 300, layer.scale)
+
+	if ap {
+		buffer.draw("combo/v3/bc.png", 0, -103, 1, auraAlpha, hudDrawOptions{})
+		buffer.draw("combo/v3/pc.png", 0, -100, 1, 1, hudDrawOptions{})
+	} else {
+		buffer.draw("combo/v3/nc.png", 0, -100, 1, 1, hudDrawOptions{})
+	}
+
+	size := 1.0
+	if progress <= 8 {
+		size = (progress/8)*0.4 + 0.6
+	}
+
+	for i, digit := range combo {
+		x := ofs + float64(i)*102
+		name := "combo/v3/n" + string(digit) + ".png"
+		if ap {
+			buffer.draw("combo/v3/b"+string(digit)+".png", x*size, 0, size, auraAlpha, hudDrawOptions{})
+			name = "combo/v3/p" + string(digit) + ".png"
+		}
+		buffer.draw(name, x*size, 0, size, 1, hudDrawOptions{})
+
+		// 数字が切り替わった直後は、ぼかした数字を加算で大きく重ねる
+		if progress > 8 && progress < 15 {
+			t := (progress - 8) / 7
+			addSize := t * 0.4
+			buffer.draw(name, x*(size+addSize), 0, size+addSize, 1-(t*0.5+0.5), hudDrawOptions{
+				additive: true,
+				blur:     t * 8,
+			})
+		}
+	}
+	return buffer
+}
+
+// renderScore は@Scoreと同じ規則でスコア・スコアバー・ランクを描画する
+func (r *overlayRenderer) renderScore(layer hudLayer, current ScoreFrame, progress float64) *hudBuffer {
+	maxDigit := int(effectFloat(layer.effect, "Max Digit", 8))
+	animSpeed := effectFloat(layer.effect, "Animation Speed", 4)
+	xAreaExpand := effectFloat(layer.effect, "X Area Expand", 0)
+	buffer := r.newBuffer(663+xAreaExpand, 200, layer.scale)
+
+	buffer.draw("score/v3/bg.png", 0, 0, 0.32, 1, hudDrawOptions{})
+	buffer.draw("score/v3/bar.png", 51.4, -4.51, 0.32, 1, hudDrawOptions{
+		mask: &hudMask{x: current.ScoreBar * 1650, size: 1650},
+	})
+	buffer.draw("score/v3/fg.png", -1, 0, 0.32, 1, hudDrawOptions{})
+
+	score := strconv.Itoa(current.Score)
+	for len(score) < maxDigit {
+		score = "n" + score
+	}
+	for _, prefix := range []string{"s", ""} {
+		for i := 0; i < maxDigit && i < len(score); i++ {
+			buffer.draw("score/v3/digit/"+prefix+score[i:i+1]+".png", -188.83+float64(i)*32.5, 40.38, 1, 1, hudDrawOptions{})
+		}
+	}
+	maxDigitOfs := -188.83 + float64(maxDigit-1)*32.5
+
+	rank := current.Rank
+	if rank == "" {
+		rank = "none"
+	}
+	buffer.draw("score/v3/rank/character/"+rank+".png", -280.99, -10.81, 0.35, 1, hudDrawOptions{})
+	buffer.draw("score/v3/rank/text/"+rank+".png", -281.66, 52.02, 0.085, 1, hudDrawOptions{})
+
+	// 加算スコアはスライドしながらフェードインする
+	if current.AddScore > 0 && progress < 30 {
+		ease := 1.0
+		if progress < 20 {
+			ease = 1 - math.Pow(1-progress/20, animSpeed)
+		}
+		addScore := "+" + strconv.Itoa(current.AddScore)
+		for _, prefix := range []string{"s", ""} {
+			for i := 0; i < len(addScore); i++ {
+				x := ease*45 + maxDigitOfs + 6.89 + float64(i)*22
+				buffer.draw("score/v3/digit/"+prefix+addScore[i:i+1]+".png", x, 51.3, 0.65, ease, hudDrawOptions{})
+			}
+		}
+	}
+	return buffer
+}
+
+// renderLife は@Lifeと同じ規則でライフを描画する
+func (r *overlayRenderer) renderLife(layer hudLayer) *hudBuffer {
+	life := int(effectFloat(layer.effect, "Life", 1000))
+	buffer := r.newBuffer(500, 150, layer.scale)
+
+	buffer.draw("life/v3/bg.png", 0, 0, 0.173, 1, hudDrawOptions{})
+	bar := "life/v3/bar/green.png"
+	if life <= 200 {
+		bar = "life/v3/bar/red.png"
+	}
+	buffer.draw(bar, 0, 0, 0.173, 1, hudDrawOptions{
+		mask: &hudMask{x: float64(life) * 1.531, size: 1800},
+	})
+
+	digits := strconv.Itoa(life)
+	for _, prefix := range []string{"s", ""} {
+		for i := len(digits) - 1; i >= 0; i-- {
+			x := 118 - float64(len(digits)-i)*22
+			buffer.draw("life/v3/digit/"+prefix+digits[i:i+1]+".png", x, -25, 0.025, 1, hudDrawOptions{})
+		}
+	}
+	return buffer
+}
+
+// renderJudgement は@Judgementと同じ規則で判定を描画する
+func (r *overlayRenderer) renderJudgement(layer hudLayer, current ScoreFrame, progress float64) *hudBuffer {
+	if current.Seconds <= 0 || progress >= 20 {
+		return nil
+	}
+	judge := int(effectFloat(layer.effect, "Judge", 1))
+	if judge < 1 || judge > len(judgeImages) {
+		judge = 1
+	}
+
+	var zoom float64
+	switch {
+	case progress < 2:
+		return nil
+	case progress < 3:
+		zoom = 0.7
+	case progress < 4:
+		zoom = 0.95
+	default:
+		zoom = 1
+	}
+
+	// Judgementはオブジェクトに直接描画するため、画像と同じ大きさのバッファを使う
+	path := "judge/v3/" + judgeImages[judge-1] + ".png"
+	src, err := r.cache.load(path)
+	if err != nil {
+		return nil
+	}
+	bounds := src.Bounds()
+	buffer := r.newBuffer(float64(bounds.Dx())*zoom, float64(bounds.Dy())*zoom, layer.scale)
+	buffer.draw(path, 0, 0, zoom, 1, hudDrawOptions{})
+	return buffer
+}
+
+// newBuffer はobj.setoption("drawtarget", "tempbuffer", w, h)に相当する描画先を作成する
+// オブジェクトの拡大率を反映した解像度で描画し、画像の拡大を1回で済ませる
+func (r *overlayRenderer) newBuffer(w, h, scale float64) *hudBuffer {
+	width := int(math.Round(w * scale))
+	height := int(math.Round(h * scale))
+	return &hudBuffer{
+		img:   image.NewNRGBA(image.Rect(0, 0, max(width, 1), max(height, 1))),
+		scale: scale,
+		cache: r.cache,
+	}
+}
+
+// hudMask はマスク（四角形・反転）の設定で、画像中心を基準にした位置とサイズを表す
+type hudMask struct {
+	x    float64
+	size float64
+}
+
+// hudDrawOptions はobj.drawの描画オプション
+type hudDrawOptions struct {
+	additive bool
+	blur     float64
+	mask     *hudMask
+}
+
+// hudBuffer はtempbufferに相当する描画先
+type hudBuffer struct {
+	img   *image.NRGBA
+	scale float64
+	cache *hudImageCache
+}
+
+// draw はobj.draw(x, y, 0, zoom, alpha)と同様に、バッファ中心を基準に画像を描画する
+func (b *hudBuffer) draw(path string, x, y, zoom, alpha float64, opts hudDrawOptions) {
+	if zoom <= 0 || alpha <= 0 {
+		return
+	}
+	scale := zoom * b.scale
+	blur := int(math.Round(opts.blur * b.scale))
+	src, err := b.cache.scaled(path, scale, blur)
+	if err != nil {
+		return
+	}
+
+	if opts.mask != nil {
+		src = applyInvertedSquareMask(src, opts.mask.x*scale, opts.mask.size*scale)
+	}
+
+	bounds := b.img.Bounds()
+	pos := image.Pt(
+		int(math.Round(float64(bounds.Dx())/2+x*b.scale-float64(src.Bounds().Dx())/2)),
+		int(math.Round(float64(bounds.Dy())/2+y*b.scale-float64(src.Bounds().Dy())/2)),
+	)
+	if opts.additive {
+		addNRGBA(b.img, src, pos, alpha)
+	} else {
+		overlayNRGBA(b.img, src, pos, alpha)
+	}
+}
+
+// applyInvertedSquareMask は画像中心から(x, 0)を中心とする四角形の内側を透明にした画像を返す
+func applyInvertedSquareMask(src *image.NRGBA, x, size float64) *image.NRGBA {
+	dst := imaging.Clone(src)
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	for py := 0; py < h; py++ {
+		ly := float64(py) + 0.5 - float64(h)/2
+		if math.Abs(ly) > size/2 {
+			continue
+		}
+		for px := 0; px < w; px++ {
+			lx := float64(px) + 0.5 - float64(w)/2
+			if math.Abs(lx-x) <= size/2 {
+				dst.Pix[py*dst.Stride+px*4+3] = 0
+			}
+		}
+	}
+	return dst
+}
+
+// overlayNRGBA はsrcをdstの指定位置に不透明度付きで通常合成する
+func overlayNRGBA(dst, src *image.NRGBA, pos image.Point, opacity float64) {
+	blendNRGBA(dst, src, pos, opacity, func(d, s []uint8, sa float64) {
+		da := float64(d[3]) / 255
+		oa := sa + da*(1-sa)
+		if oa <= 0 {
+			return
+		}
+		for c := 0; c < 3; c++ {
+			d[c] = uint8(math.Round((float64(s[c])*sa + float64(d[c])*da*(1-sa)) / oa))
+		}
+		d[3] = uint8(math.Round(oa * 255))
+	})
+}
+
+// addNRGBA はsrcをdstの指定位置に不透明度付きで加算合成する
+func addNRGBA(dst, src *image.NRGBA, pos image.Point, opacity float64) {
+	blendNRGBA(dst, src, pos, opacity, func(d, s []uint8, sa float64) {
+		da := float64(d[3]) / 255
+		oa := math.Min(da+sa, 1)
+		for c := 0; c < 3; c++ {
+			v := (float64(d[c])*da + float64(s[c])*sa) / oa
+			d[c] = uint8(math.Min(255, math.Round(v)))
+		}
+		d[3] = uint8(math.Round(oa * 255))
+	})
+}
+
+// blendNRGBA は重なる範囲のピクセルごとに合成関数を呼び出す
+func blendNRGBA(dst, src *image.NRGBA, pos image.Point, opacity float64, blend func(d, s []uint8, sa float64)) {
+	srcBounds := src.Bounds()
+	area := image.Rectangle{Min: pos, Max: pos.Add(srcBounds.Size())}.Intersect(dst.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			si := src.PixOffset(srcBounds.Min.X+x-pos.X, srcBounds.Min.Y+y-pos.Y)
+			s := src.Pix[si : si+4 : si+4]
+			sa := float64(s[3]) / 255 * opacity
+			if sa <= 0 {
+				continue
+			}
+			di := dst.PixOffset(x, y)
+			blend(dst.Pix[di:di+4:di+4], s, sa)
+		}
+	}
+}
+
+// hudImageCache はアセット画像と、拡大・ぼかし済みの画像をキャッシュする
+type hudImageCache struct {
+	mu       sync.Mutex
+	original map[string]image.Image
+	resized  map[string]*image.NRGBA
+}
+
+func newHUDImageCache() *hudImageCache {
+	return &hudImageCache{
+		original: map[string]image.Image{},
+		resized:  map[string]*image.NRGBA{},
+	}
+}
+
+// load はassets以下の画像を読み込む
+func (c *hudImageCache) load(path string) (image.Image, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadLocked(path)
+}
+
+func (c *hudImageCache) loadLocked(path string) (image.Image, error) {
+	if img, ok := c.original[path]; ok {
+		return img, nil
+	}
+	img, err := loadAssetImage(filepath.Join("assets", path))
+	if err != nil {
+		return nil, fmt.Errorf("画像の読み込みに失敗しました (%s): %w", path, err)
+	}
+	c.original[path] = img
+	return img, nil
+}
+
+// scaled は拡大率とぼかしの範囲（ピクセル）を反映した画像を返す
+// ぼかしはAviUtlと同様に画像の周囲を範囲の分だけ広げてからかける
+// 縮小・ぼかしはロックの外で行い、同時に同じ画像を作った場合は先に登録された方を使う
+func (c *hudImageCache) scaled(path string, scale float64, blur int) (*image.NRGBA, error) {
+	c.mu.Lock()
+	src, err := c.loadLocked(path)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	w := int(math.Round(float64(src.Bounds().Dx()) * scale))
+	h := int(math.Round(float64(src.Bounds().Dy()) * scale))
+	key := fmt.Sprintf("%s:%d:%d:%d", path, w, h, blur)
+	cached, ok := c.resized[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var img *image.NRGBA
+	if w <= 0 || h <= 0 {
+		img = image.NewNRGBA(image.Rect(0, 0, 0, 0))
+	} else {
+		img = imaging.Resize(src, w, h, imaging.Linear)
+	}
+	if blur > 0 {
+		padded := image.NewNRGBA(image.Rect(0, 0, w+blur*2, h+blur*2))
+		overlayNRGBA(padded, img, image.Pt(blur, blur), 1)
+		img = imaging.Blur(padded, float64(blur)/2)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.resized[key]; ok {
+		return cached, nil
+	}
+	c.resized[key] = img
+	return img, nil
+}
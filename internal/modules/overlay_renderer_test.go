@@ -0,0 +1,163 @@
+package modules
+
+import (
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useRepoAssets はutils.ResourcePathがリポジトリのassetsを指すようにする
+// （SNAP環境と同じくos.Args[0]のディレクトリを基準にさせる）
+func useRepoAssets(t *testing.T) {
+	t.Helper()
+	t.Setenv("SNAP", "")
+	saved := os.Args[0]
+	os.Args[0] = filepath.Join("..", "..", "sekai-overlay")
+	t.Cleanup(func() { os.Args[0] = saved })
+}
+
+// alphaBounds はアルファが0でない画素を囲む矩形を返す
+func alphaBounds(img *image.NRGBA) image.Rectangle {
+	var bounds image.Rectangle
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.NRGBAAt(x, y).A != 0 {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+func TestOverlayRendererLayout(t *testing.T) {
+	useRepoAssets(t)
+	tmpl := AliasTemplate{Name: DefaultAliasTemplate, Path: filepath.Join("..", "..", "assets", "alias", "template.object")}
+	frames := []ScoreFrame{
+		{Seconds: 0, Rank: "none"},
+		{Seconds: 1, Combo: 1, Score: 3456, AddScore: 3456, Rank: "d", ScoreBar: 0.1},
+		{Seconds: 1.5, Combo: 2, Score: 6912, AddScore: 3456, Rank: "d", ScoreBar: 0.2},
+	}
+
+	for _, tc := range []struct {
+		resolution string
+		layout     string
+		// 1080p基準の@SekaiObjects.obj2の配置（テンプレートのレイアウトプリセット）
+		score, combo [2]float64
+	}{
+		{"1080p", LayoutLandscape, [2]float64{-584.84, -468.64}, [2]float64{672, -62}},
+		{"4k", LayoutLandscape, [2]float64{-584.84, -468.64}, [2]float64{672, -62}},
+		{"1080p", LayoutPortrait, [2]float64{-190, -780}, [2]float64{270, -150}},
+	} {
+		t.Run(tc.resolution+"_"+tc.layout, func(t *testing.T) {
+			format, err := NewVideoFormat(60, tc.resolution, tc.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered, err := executeAliasTemplate(tmpl, testAliasData(format), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			objectFile, err := ParseObjectFile(rendered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			renderer, err := newOverlayRenderer(objectFile, frames, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if canvas := renderer.renderFrame(renderer.init.Start() - 1); canvas != nil {
+				t.Error("InitSettingsの開始前に描画されました")
+			}
+
+			// 2つ目のノーツから1秒後（コンボ・加算スコアのアニメーションが終わった後）
+			frame := renderer.init.Start() + int(renderer.offset) + format.FPS*5/2
+			canvas := renderer.renderFrame(frame)
+			if canvas == nil {
+				t.Fatal("HUDが描画されていません")
+			}
+			if got := canvas.Bounds().Size(); got != image.Pt(format.Width, format.Height) {
+				t.Fatalf("size = %v", got)
+			}
+
+			// 各レイヤーは標準描画の座標を中心とした描画範囲の中に収まる
+			var union image.Rectangle
+			opaque := false
+			for _, name := range []string{effectScore, effectCombo, effectLife} {
+				single := *renderer
+				single.layers = nil
+				for _, layer := range renderer.layers {
+					if layer.effect.Name == name {
+						single.layers = append(single.layers, layer)
+					}
+				}
+				if len(single.layers) != 1 {
+					t.Fatalf("%s: レイヤーの数 = %d", name, len(single.layers))
+				}
+				layer := single.layers[0]
+
+				var buffer *hudBuffer
+				current := renderer.currentScoreFrame(float64(frame-renderer.init.Start()) - renderer.offset)
+				switch name {
+				case effectScore:
+					checkLayerPosition(t, name, layer, format, tc.score)
+					buffer = renderer.renderScore(layer, current, 60)
+				case effectCombo:
+					checkLayerPosition(t, name, layer, format, tc.combo)
+					buffer = renderer.renderCombo(layer, current, 60, 0)
+				case effectLife:
+					buffer = renderer.renderLife(layer)
+				}
+
+				img := single.renderFrame(frame)
+				if img == nil {
+					t.Fatalf("%s: 描画されていません", name)
+				}
+				drawn := alphaBounds(img)
+				cx := float64(format.Width)/2 + layer.x
+				cy := float64(format.Height)/2 + layer.y
+				size := buffer.img.Bounds().Size()
+				area := image.Rect(0, 0, size.X, size.Y).Add(image.Pt(int(math.Round(cx-float64(size.X)/2)), int(math.Round(cy-float64(size.Y)/2))))
+				if !image.Pt(int(cx), int(cy)).In(drawn.Inset(-1)) {
+					t.Errorf("%s: 描画範囲 %v が座標 (%.1f, %.1f) を含みません", name, drawn, cx, cy)
+				}
+				if !drawn.In(area) {
+					t.Errorf("%s: 描画範囲 %v がバッファ %v からはみ出しています", name, drawn, area)
+				}
+				union = union.Union(area)
+				for i := 3; i < len(img.Pix); i += 4 {
+					if img.Pix[i] == 255 {
+						opaque = true
+						break
+					}
+				}
+			}
+			if !opaque {
+				t.Error("不透明な画素がありません")
+			}
+
+			// 判定の表示が終わった後は、HUDの外側は透明のまま
+			for y := 0; y < format.Height; y++ {
+				for x := 0; x < format.Width; x++ {
+					if !image.Pt(x, y).In(union) && canvas.NRGBAAt(x, y).A != 0 {
+						t.Fatalf("HUDの外側 (%d, %d) が透明ではありません: %v", x, y, canvas.NRGBAAt(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+// checkLayerPosition はレイヤーの座標と拡大率が出力解像度に合わせたobj2の配置と一致するかを確認する
+func checkLayerPosition(t *testing.T, name string, layer hudLayer, format VideoFormat, want [2]float64) {
+	t.Helper()
+	x, y := format.Pos(want[0]), format.Pos(want[1])
+	if math.Abs(layer.x-x) > 0.01 || math.Abs(layer.y-y) > 0.01 {
+		t.Errorf("%s: 座標 = (%.2f, %.2f), want (%.2f, %.2f)", name, layer.x, layer.y, x, y)
+	}
+	if scale := format.Pos(100) / 100; math.Abs(layer.scale-scale) > 0.001 {
+		t.Errorf("%s: 拡大率 = %.3f, want %.3f", name, layer.scale, scale)
+	}
+}
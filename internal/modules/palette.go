@@ -0,0 +1,320 @@
+package modules
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// paletteClusters はジャケットの色をまとめるクラスタ数
+	paletteClusters = 6
+	// paletteSampleSize はk-meansの前にジャケットを縮小するサイズ
+	paletteSampleSize = 64
+	// paletteIterations はk-meansの最大反復回数
+	paletteIterations = 24
+	// accentMinWeight はアクセントカラーとして扱うクラスタの最小の割合
+	accentMinWeight = 0.03
+)
+
+// Palette はジャケットから抽出した配色
+type Palette struct {
+	// Dominant は一番面積の大きい色
+	Dominant color.NRGBA
+	// Accent はDominantと異なる色のうち、鮮やかで目立つ色
+	Accent color.NRGBA
+	// Colors は抽出したすべての色（割合の大きい順）
+	Colors []PaletteColor
+}
+
+// PaletteColor は抽出した色とジャケット内での割合
+type PaletteColor struct {
+	Color  color.NRGBA
+	Weight float64
+}
+
+// ThemeColors はskobj_data.jsonに出力するテーマカラー（#rrggbb形式）
+type ThemeColors struct {
+	Dominant string   `json:"dominant"`
+	Accent   string   `json:"accent"`
+	Shade    string   `json:"shade"`
+	Palette  []string `json:"palette"`
+}
+
+// LoadJacketPalette はジャケット画像から配色を抽出する
+func LoadJacketPalette(distDir string) (Palette, error) {
+	jacket, err := imaging.Open(jacketImagePath(distDir))
+	if err != nil {
+		return Palette{}, fmt.Errorf("ジャケット画像の読み込みに失敗しました: %w", err)
+	}
+	return ExtractPalette(jacket), nil
+}
+
+// ExtractPalette は画像の色をk-meansでまとめて配色を求める
+// 初期値は最も離れた色から順に選ぶので、同じ画像からは常に同じ結果になる
+func ExtractPalette(img image.Image) Palette {
+	small := imaging.Resize(img, paletteSampleSize, paletteSampleSize, imaging.Box)
+
+	var pixels [][3]float64
+	for i := 0; i < len(small.Pix); i += 4 {
+		if small.Pix[i+3] < 128 {
+			continue
+		}
+		pixels = append(pixels, [3]float64{float64(small.Pix[i]), float64(small.Pix[i+1]), float64(small.Pix[i+2])})
+	}
+	if len(pixels) == 0 {
+		gray := color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+		return Palette{Dominant: gray, Accent: gray, Colors: []PaletteColor{{Color: gray, Weight: 1}}}
+	}
+
+	centers := initialCenters(pixels, min(paletteClusters, len(pixels)))
+	assign := make([]int, len(pixels))
+	var counts []int
+	for iter := 0; iter < paletteIterations; iter++ {
+		changed := false
+		for i, p := range pixels {
+			nearest := nearestCenter(centers, p)
+			if nearest != assign[i] {
+				changed = true
+				assign[i] = nearest
+			}
+		}
+
+		sums := make([][3]float64, len(centers))
+		counts = make([]int, len(centers))
+		for i, p := range pixels {
+			c := assign[i]
+			sums[c][0] += p[0]
+			sums[c][1] += p[1]
+			sums[c][2] += p[2]
+			counts[c]++
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				n := float64(counts[c])
+				centers[c] = [3]float64{sums[c][0] / n, sums[c][1] / n, sums[c][2] / n}
+			}
+		}
+		if !changed && iter > 0 {
+			break
+		}
+	}
+
+	var colors []PaletteColor
+	for c, center := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		colors = append(colors, PaletteColor{
+			Color:  color.NRGBA{R: clampUint8(center[0]), G: clampUint8(center[1]), B: clampUint8(center[2]), A: 0xff},
+			Weight: float64(counts[c]) / float64(len(pixels)),
+		})
+	}
+	sort.SliceStable(colors, func(i, j int) bool {
+		return colors[i].Weight > colors[j].Weight
+	})
+
+	palette := Palette{Dominant: colors[0].Color, Accent: colors[0].Color, Colors: colors}
+	best := -1.0
+	for _, c := range colors[1:] {
+		if c.Weight < accentMinWeight {
+			continue
+		}
+		_, s, v := rgbToHSV(c.Color)
+		score := s * v * math.Sqrt(c.Weight) * colorDistance(c.Color, palette.Dominant)
+		if score > best {
+			best = score
+			palette.Accent = c.Color
+		}
+	}
+	return palette
+}
+
+// initialCenters は平均に一番近い色から始めて、既存の中心から最も離れた色を順に中心にする
+func initialCenters(pixels [][3]float64, k int) [][3]float64 {
+	var mean [3]float64
+	for _, p := range pixels {
+		mean[0] += p[0]
+		mean[1] += p[1]
+		mean[2] += p[2]
+	}
+	n := float64(len(pixels))
+	mean = [3]float64{mean[0] / n, mean[1] / n, mean[2] / n}
+
+	centers := [][3]float64{pixels[nearestCenter(pixels, mean)]}
+	distances := make([]float64, len(pixels))
+	for i, p := range pixels {
+		distances[i] = squaredDistance(p, centers[0])
+	}
+	for len(centers) < k {
+		farthest := 0
+		for i, d := range distances {
+			if d > distances[farthest] {
+				farthest = i
+			}
+		}
+		if distances[farthest] == 0 {
+			break
+		}
+		centers = append(centers, pixels[farthest])
+		for i, p := range pixels {
+			distances[i] = math.Min(distances[i], squaredDistance(p, pixels[farthest]))
+		}
+	}
+	return centers
+}
+
+// nearestCenter は一番近い中心のインデックスを返す
+func nearestCenter(centers [][3]float64, p [3]float64) int {
+	nearest, best := 0, math.MaxFloat64
+	for i, c := range centers {
+		if d := squaredDistance(p, c); d < best {
+			nearest, best = i, d
+		}
+	}
+	return nearest
+}
+
+// squaredDistance はRGB空間での距離の2乗を返す
+func squaredDistance(a, b [3]float64) float64 {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// colorDistance は2色の距離を0〜1で返す
+func colorDistance(a, b color.NRGBA) float64 {
+	d := squaredDistance([3]float64{float64(a.R), float64(a.G), float64(a.B)}, [3]float64{float64(b.R), float64(b.G), float64(b.B)})
+	return math.Sqrt(d) / (255 * math.Sqrt(3))
+}
+
+// Shade はDominantの色相で、開始画面の背景に重ねる落ち着いた色を返す
+// 彩度と明度を標準の色（#4f4f7d）に近づけるので、文字が読みにくくならない
+func (p Palette) Shade() color.NRGBA {
+	h, s, _ := rgbToHSV(p.Dominant)
+	return hsvToRGB(h, math.Min(s, 0.45), 0.49)
+}
+
+// Theme はskobj_data.jsonに出力するテーマカラーを返す
+func (p Palette) Theme() *ThemeColors {
+	theme := &ThemeColors{
+		Dominant: hexColor(p.Dominant),
+		Accent:   hexColor(p.Accent),
+		Shade:    hexColor(p.Shade()),
+	}
+	for _, c := range p.Colors {
+		theme.Palette = append(theme.Palette, hexColor(c.Color))
+	}
+	return theme
+}
+
+// Resolve はレイアウトなどで指定された色の名前（dominant/accent/shade/#rrggbb）を色に変換する
+func (p Palette) Resolve(name string) (color.NRGBA, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "dominant":
+		return p.Dominant, nil
+	case "accent":
+		return p.Accent, nil
+	case "shade":
+		return p.Shade(), nil
+	}
+	return parseHexColor(name)
+}
+
+// hexColor は色を#rrggbb形式の文字列にする
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// parseHexColor は#rrggbb形式の文字列を色に変換する
+func parseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("色 '%s' が不正です (dominant/accent/shade/#rrggbb)", s)
+	}
+	return color.NRGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, nil
+}
+
+// rgbToHSV は色を色相(0〜360)・彩度・明度(0〜1)に変換する
+func rgbToHSV(c color.NRGBA) (h, s, v float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC, minC := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	v = maxC
+	if maxC == 0 {
+		return 0, 0, 0
+	}
+	delta := maxC - minC
+	s = delta / maxC
+	if delta == 0 {
+		return 0, s, v
+	}
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+// hsvToRGB は色相・彩度・明度を色に変換する
+func hsvToRGB(h, s, v float64) color.NRGBA {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.NRGBA{R: clampUint8((r + m) * 255), G: clampUint8((g + m) * 255), B: clampUint8((b + m) * 255), A: 0xff}
+}
+
+// tintImage は画像の明るさを保ったまま色を付ける（amountは0〜1）
+// 中間の明るさが指定した色になり、黒と白はそのまま残る
+func tintImage(img image.Image, tint color.NRGBA, amount float64) *image.NRGBA {
+	dst := imaging.Clone(img)
+	t := [3]float64{float64(tint.R) / 255, float64(tint.G) / 255, float64(tint.B) / 255}
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	parallelRows(0, h, func(y int) {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+w*4]
+		for i := 0; i < len(row); i += 4 {
+			if row[i+3] == 0 {
+				continue
+			}
+			l := (0.299*float64(row[i]) + 0.587*float64(row[i+1]) + 0.114*float64(row[i+2])) / 255
+			for c := 0; c < 3; c++ {
+				var colored float64
+				if l < 0.5 {
+					colored = 2 * l * t[c]
+				} else {
+					colored = 1 - 2*(1-l)*(1-t[c])
+				}
+				row[i+c] = clampUint8(float64(row[i+c])*(1-amount) + colored*255*amount)
+			}
+		}
+	})
+	return dst
+}
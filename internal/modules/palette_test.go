@@ -0,0 +1,81 @@
+package modules
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestLoadJacketPaletteTwoColors(t *testing.T) {
+	// 左3/4が暗い青、右1/4が鮮やかな赤のジャケット（境界は縮小後も画素の境目になる）
+	navy := color.NRGBA{0x20, 0x30, 0x60, 0xff}
+	red := color.NRGBA{0xe0, 0x30, 0x30, 0xff}
+	jacket := imaging.New(512, 512, navy)
+	for y := 0; y < 512; y++ {
+		for x := 384; x < 512; x++ {
+			jacket.SetNRGBA(x, y, red)
+		}
+	}
+	distDir := t.TempDir()
+	if err := imaging.Save(jacket, filepath.Join(distDir, "jacket.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	palette, err := LoadJacketPalette(distDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if palette.Dominant != navy || palette.Accent != red {
+		t.Errorf("Dominant = %v, Accent = %v", palette.Dominant, palette.Accent)
+	}
+	if len(palette.Colors) != 2 {
+		t.Fatalf("色の数 = %d, want 2 (%v)", len(palette.Colors), palette.Colors)
+	}
+	for i, want := range []PaletteColor{{navy, 0.75}, {red, 0.25}} {
+		got := palette.Colors[i]
+		if got.Color != want.Color || math.Abs(got.Weight-want.Weight) > 1e-9 {
+			t.Errorf("Colors[%d] = %v, want %v", i, got, want)
+		}
+	}
+
+	// Shadeは青の色相のまま、標準の色に近い彩度・明度になる
+	h, s, v := rgbToHSV(palette.Shade())
+	if math.Abs(h-225) > 2 || s > 0.46 || math.Abs(v-0.49) > 0.01 {
+		t.Errorf("Shade = %v (h=%.1f, s=%.2f, v=%.2f)", palette.Shade(), h, s, v)
+	}
+
+	theme := palette.Theme()
+	if theme.Dominant != "#203060" || theme.Accent != "#e03030" || len(theme.Palette) != 2 {
+		t.Errorf("Theme = %+v", theme)
+	}
+	for name, want := range map[string]color.NRGBA{"dominant": navy, " Accent ": red, "#e03030": red} {
+		if got, err := palette.Resolve(name); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := palette.Resolve("#12345"); err == nil {
+		t.Error("不正な色がエラーになりません")
+	}
+}
+
+func TestExtractPaletteIgnoresTransparent(t *testing.T) {
+	// 透明な部分の色は配色に含めない
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if x < 16 {
+				img.SetNRGBA(x, y, color.NRGBA{0x30, 0xa0, 0x40, 0xff})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{0xff, 0x00, 0xff, 0x00})
+			}
+		}
+	}
+	palette := ExtractPalette(img)
+	if len(palette.Colors) != 1 || palette.Dominant != (color.NRGBA{0x30, 0xa0, 0x40, 0xff}) {
+		t.Errorf("palette = %+v", palette)
+	}
+}
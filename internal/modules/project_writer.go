@@ -0,0 +1,53 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AviUtl2プロジェクトファイルの設定値
+const (
+	projectFileName    = "project.aup2"
+	projectVersion     = 2001900
+	projectAudioRate   = 48000
+	projectSceneName   = "Root"
+	projectDisplayZoom = 10000
+)
+
+// writeProjectFile はエイリアスオブジェクトの内容からAviUtl2のプロジェクトファイル(.aup2)を書き出す
+// シーン設定（解像度・フレームレート）は生成パラメータから決定し、オブジェクトはテンプレートの展開結果をそのまま使用する
+func writeProjectFile(distDir, distPath string, objectFile *ObjectFile, format VideoFormat) error {
+	outputPath := filepath.Join(distDir, projectFileName)
+
+	var b strings.Builder
+	b.WriteString("[project]\n")
+	fmt.Fprintf(&b, "version=%d\n", projectVersion)
+	fmt.Fprintf(&b, "file=%s\\%s\n", distPath, projectFileName)
+	b.WriteString("display.scene=0\n")
+
+	b.WriteString("[scene.0]\n")
+	b.WriteString("scene=0\n")
+	fmt.Fprintf(&b, "name=%s\n", projectSceneName)
+	fmt.Fprintf(&b, "video.width=%d\n", format.Width)
+	fmt.Fprintf(&b, "video.height=%d\n", format.Height)
+	fmt.Fprintf(&b, "video.rate=%d\n", format.FPS)
+	b.WriteString("video.scale=1\n")
+	fmt.Fprintf(&b, "audio.rate=%d\n", projectAudioRate)
+	b.WriteString("cursor.frame=0\n")
+	b.WriteString("display.frame=0\n")
+	b.WriteString("display.layer=0\n")
+	fmt.Fprintf(&b, "display.zoom=%d\n", projectDisplayZoom)
+	b.WriteString("display.order=0\n")
+	b.WriteString("display.camera=\n")
+
+	b.WriteString(objectFile.String())
+
+	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("プロジェクトファイルの書き込みに失敗しました: %w", err)
+	}
+
+	fmt.Printf("プロジェクトファイルを '%s' に保存しました。(%s, %dフレーム)\n", outputPath, format, objectFile.EndFrame()+1)
+	return nil
+}
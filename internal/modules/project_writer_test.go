@@ -0,0 +1,66 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testObject はプロジェクトファイルのテストに使う.object
+const testObject = `[0]
+layer=1
+frame=0,299
+[0.0]
+effect.name=画像ファイル
+ファイル=C:\dist\background.png
+[0.1]
+effect.name=標準描画
+X=0.00
+Y=0.00
+[1]
+layer=2
+frame=30,299
+[1.0]
+effect.name=図形
+図形の種類=四角形
+`
+
+func TestWriteProjectFileGolden(t *testing.T) {
+	objectFile, err := ParseObjectFile(testObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		fps        int
+		resolution string
+		layout     string
+	}{
+		{"project_1080p60.aup2", 60, "1080p", LayoutLandscape},
+		{"project_portrait_4k30.aup2", 30, "4k", LayoutPortrait},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := NewVideoFormat(tc.fps, tc.resolution, tc.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			distDir := t.TempDir()
+			if err := writeProjectFile(distDir, `C:\dist`, objectFile, format); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(distDir, projectFileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", tc.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("プロジェクトファイルが一致しません\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
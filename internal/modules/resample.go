@@ -0,0 +1,200 @@
+package modules
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// ResampleFilter は背景のジャケットを射影変換するときの補間方法
+type ResampleFilter string
+
+// 補間方法の種類
+const (
+	ResampleBilinear ResampleFilter = "bilinear"
+	ResampleBicubic  ResampleFilter = "bicubic"
+	ResampleLanczos  ResampleFilter = "lanczos"
+)
+
+// DefaultResampleFilter はデフォルトの補間方法（以前のバージョンと同じバイリニア）
+// 辺のアンチエイリアス・縮小画像からの補間・四捨五入により、出力は以前のバージョンと完全には一致しない
+const DefaultResampleFilter = ResampleBilinear
+
+// ResampleFilterNames は選択できる補間方法の一覧
+var ResampleFilterNames = []string{string(ResampleBilinear), string(ResampleBicubic), string(ResampleLanczos)}
+
+// edgeSamples は四角形の辺にかかるピクセルの被覆率を求めるときの1辺あたりのサンプル数
+const edgeSamples = 4
+
+// ParseResampleFilter は補間方法の名前を解析する（空の場合はデフォルト）
+func ParseResampleFilter(name string) (ResampleFilter, error) {
+	switch filter := ResampleFilter(strings.ToLower(strings.TrimSpace(name))); filter {
+	case "":
+		return DefaultResampleFilter, nil
+	case ResampleBilinear, ResampleBicubic, ResampleLanczos:
+		return filter, nil
+	}
+	return "", fmt.Errorf("補間方法 '%s' はサポートされていません (%s)", name, strings.Join(ResampleFilterNames, "/"))
+}
+
+// resampleKernel は補間に使う1次元のカーネル
+type resampleKernel struct {
+	support int
+	weight  func(t float64) float64
+}
+
+// kernel は補間方法に対応するカーネルを返す
+func (f ResampleFilter) kernel() resampleKernel {
+	switch f {
+	case ResampleBilinear:
+		return resampleKernel{support: 1, weight: func(t float64) float64 {
+			return math.Max(0, 1-math.Abs(t))
+		}}
+	case ResampleLanczos:
+		return resampleKernel{support: 3, weight: func(t float64) float64 {
+			t = math.Abs(t)
+			if t == 0 {
+				return 1
+			}
+			if t >= 3 {
+				return 0
+			}
+			return 3 * math.Sin(math.Pi*t) * math.Sin(math.Pi*t/3) / (math.Pi * math.Pi * t * t)
+		}}
+	}
+	// Catmull-Rom (a = -0.5)
+	return resampleKernel{support: 2, weight: func(t float64) float64 {
+		t = math.Abs(t)
+		switch {
+		case t < 1:
+			return 1.5*t*t*t - 2.5*t*t + 1
+		case t < 2:
+			return -0.5*t*t*t + 2.5*t*t - 4*t + 2
+		}
+		return 0
+	}}
+}
+
+// sample は(x, y)の色をカーネルで補間してdst（NRGBAの4バイト）に書き込む
+// 画像外の画素は端の画素で補い、アルファで重み付けして透明部分の色が混ざらないようにする
+// coverageは書き込むアルファに掛ける被覆率
+func (k resampleKernel) sample(img *image.NRGBA, x, y, coverage float64, dst []uint8) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	x0 := int(math.Floor(x)) - k.support + 1
+	y0 := int(math.Floor(y)) - k.support + 1
+	taps := k.support * 2
+
+	var wx [6]float64
+	for i := 0; i < taps; i++ {
+		wx[i] = k.weight(x - float64(x0+i))
+	}
+
+	var r, g, b, a, total float64
+	for j := 0; j < taps; j++ {
+		wy := k.weight(y - float64(y0+j))
+		if wy == 0 {
+			continue
+		}
+		sy := min(max(y0+j, 0), h-1)
+		row := img.Pix[sy*img.Stride:]
+		for i := 0; i < taps; i++ {
+			weight := wx[i] * wy
+			if weight == 0 {
+				continue
+			}
+			sx := min(max(x0+i, 0), w-1)
+			p := row[sx*4 : sx*4+4 : sx*4+4]
+			alpha := float64(p[3]) * weight
+			r += float64(p[0]) * alpha
+			g += float64(p[1]) * alpha
+			b += float64(p[2]) * alpha
+			a += alpha
+			total += weight
+		}
+	}
+
+	if a <= 0 || total == 0 {
+		dst[0], dst[1], dst[2], dst[3] = 0, 0, 0, 0
+		return
+	}
+	dst[0] = clampUint8(r / a)
+	dst[1] = clampUint8(g / a)
+	dst[2] = clampUint8(b / a)
+	dst[3] = clampUint8(a / total * coverage)
+}
+
+// clampUint8 は値を0〜255に丸める
+func clampUint8(v float64) uint8 {
+	return uint8(math.Round(clampFloat(v, 0, 255)))
+}
+
+// quadCoverage は出力ピクセル(x, y)のうち、射影変換した元画像の範囲に含まれる割合を返す
+// 四隅がすべて内側なら1とし、辺にかかるピクセルのみ格子状にサンプリングする
+func quadCoverage(m [9]float64, x, y int, w, h int) float64 {
+	inside := func(px, py float64) bool {
+		sx, sy := applyInversePerspective(m, px, py)
+		return sx >= 0 && sx <= float64(w-1) && sy >= 0 && sy <= float64(h-1)
+	}
+
+	fx, fy := float64(x), float64(y)
+	if inside(fx-0.5, fy-0.5) && inside(fx+0.5, fy-0.5) && inside(fx-0.5, fy+0.5) && inside(fx+0.5, fy+0.5) {
+		return 1
+	}
+
+	count := 0
+	for j := 0; j < edgeSamples; j++ {
+		py := fy - 0.5 + (float64(j)+0.5)/edgeSamples
+		for i := 0; i < edgeSamples; i++ {
+			if inside(fx-0.5+(float64(i)+0.5)/edgeSamples, py) {
+				count++
+			}
+		}
+	}
+	return float64(count) / (edgeSamples * edgeSamples)
+}
+
+// mipmapFor は元画像が変換先の四角形より2倍以上大きい場合に、半分ずつ縮小した画像を返す
+// 四角形の一番長い辺を基準にするので、手前側の辺がぼやけることはない
+func mipmapFor(src *image.NRGBA, quad []image.Point) *image.NRGBA {
+	edge := func(a, b image.Point) float64 {
+		return math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))
+	}
+	// quadは左上・右上・左下・右下の順
+	width := math.Max(edge(quad[0], quad[1]), edge(quad[2], quad[3]))
+	height := math.Max(edge(quad[0], quad[2]), edge(quad[1], quad[3]))
+	if width < 1 || height < 1 {
+		return src
+	}
+
+	level := src
+	for float64(level.Rect.Dx())/2 >= width && float64(level.Rect.Dy())/2 >= height {
+		level = halveNRGBA(level)
+	}
+	return level
+}
+
+// halveNRGBA は2x2ピクセルの平均で画像を半分のサイズに縮小する
+func halveNRGBA(src *image.NRGBA) *image.NRGBA {
+	w, h := src.Rect.Dx()/2, src.Rect.Dy()/2
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	parallelRows(0, h, func(y int) {
+		for x := 0; x < w; x++ {
+			var r, g, b, a float64
+			for _, offset := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				i := (y*2+offset[1])*src.Stride + (x*2+offset[0])*4
+				alpha := float64(src.Pix[i+3])
+				r += float64(src.Pix[i]) * alpha
+				g += float64(src.Pix[i+1]) * alpha
+				b += float64(src.Pix[i+2]) * alpha
+				a += alpha
+			}
+			p := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4 : y*dst.Stride+x*4+4]
+			if a > 0 {
+				p[0], p[1], p[2] = clampUint8(r/a), clampUint8(g/a), clampUint8(b/a)
+				p[3] = clampUint8(a / 4)
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,124 @@
+package modules
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestResampleKernelWeights(t *testing.T) {
+	for _, tc := range []struct {
+		filter ResampleFilter
+		points map[float64]float64
+		// sumTolerance は整数間隔の重みの合計と1との許容誤差（Lanczosは正規化しないと1にならない）
+		sumTolerance float64
+	}{
+		{ResampleBilinear, map[float64]float64{0: 1, 0.25: 0.75, -0.5: 0.5, 1: 0, 1.5: 0}, 1e-12},
+		{ResampleBicubic, map[float64]float64{0: 1, 0.5: 0.5625, -1: 0, 1.5: -0.0625, 2: 0, 2.5: 0}, 1e-12},
+		{ResampleLanczos, map[float64]float64{0: 1, 1: 0, -2: 0, 3: 0, 3.5: 0}, 0.01},
+	} {
+		t.Run(string(tc.filter), func(t *testing.T) {
+			k := tc.filter.kernel()
+			for x, want := range tc.points {
+				if got := k.weight(x); math.Abs(got-want) > 1e-12 {
+					t.Errorf("weight(%v) = %v, want %v", x, got, want)
+				}
+			}
+			for _, frac := range []float64{0.1, 0.25, 0.5, 0.75} {
+				sum := 0.0
+				for i := -k.support; i <= k.support; i++ {
+					sum += k.weight(frac - float64(i))
+				}
+				if math.Abs(sum-1) > tc.sumTolerance {
+					t.Errorf("位置%vの重みの合計 = %v", frac, sum)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleKernelSample(t *testing.T) {
+	// 左半分が不透明な赤、右半分が透明な緑の画像
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 4 {
+				img.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{0, 255, 0, 0})
+			}
+		}
+	}
+
+	for _, filter := range []ResampleFilter{ResampleBilinear, ResampleBicubic, ResampleLanczos} {
+		k := filter.kernel()
+		dst := make([]uint8, 4)
+		k.sample(img, 1, 3, 1, dst)
+		if (color.NRGBA{dst[0], dst[1], dst[2], dst[3]}) != (color.NRGBA{255, 0, 0, 255}) {
+			t.Errorf("%s: 内側の画素 = %v", filter, dst)
+		}
+		// 境界をまたいでも透明な画素の色は混ざらない
+		k.sample(img, 3.5, 3, 0.5, dst)
+		if dst[0] != 255 || dst[1] != 0 || dst[3] == 0 || dst[3] > 128 {
+			t.Errorf("%s: 境界の画素 = %v", filter, dst)
+		}
+	}
+}
+
+func TestQuadCoverage(t *testing.T) {
+	identity := [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
+	for _, tc := range []struct {
+		name string
+		x, y int
+		want float64
+	}{
+		{"内側", 5, 5, 1},
+		{"辺", 0, 5, 0.5},
+		{"角", 9, 9, 0.25},
+		{"外側", -2, 5, 0},
+	} {
+		if got := quadCoverage(identity, tc.x, tc.y, 10, 10); got != tc.want {
+			t.Errorf("%s: quadCoverage(%d, %d) = %v, want %v", tc.name, tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestMipmapFor(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	square := func(size int) []image.Point {
+		return []image.Point{{0, 0}, {size, 0}, {0, size}, {size, size}}
+	}
+
+	for _, tc := range []struct {
+		name string
+		quad []image.Point
+		want int
+	}{
+		{"縮小しない", square(600), 1024},
+		{"2段階縮小", square(200), 256},
+		{"ちょうど半分", square(512), 512},
+		{"潰れた四角形", square(0), 1024},
+	} {
+		got := mipmapFor(src, tc.quad)
+		if got.Rect.Dx() != tc.want || got.Rect.Dy() != tc.want {
+			t.Errorf("%s: size = %v, want %d", tc.name, got.Rect.Size(), tc.want)
+		}
+	}
+	if mipmapFor(src, square(600)) != src {
+		t.Error("縮小しない場合は元の画像を返す必要があります")
+	}
+}
+
+func TestHalveNRGBA(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{0, 0, 255, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{255, 0, 0, 0})
+	src.SetNRGBA(0, 1, color.NRGBA{255, 0, 0, 0})
+	src.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 0})
+
+	got := halveNRGBA(src).NRGBAAt(0, 0)
+	if want := (color.NRGBA{0, 0, 255, 64}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 
 	"sekai-overlay-go/internal/config"
 	"sekai-overlay-go/internal/utils"
@@ -29,11 +28,38 @@ type ScoreFrame struct {
 	ScoreBar float64 `json:"score_bar"`
 }
 
+// ChartTiming は譜面のタイミング情報を表す構造体
+type ChartTiming struct {
+	FirstNoteTime float64
+	LastNoteTime  float64
+	BpmChanges    []BpmChange
+}
+
+// TimeAtBeat は指定されたビート位置の時間（秒）を返す
+func (t ChartTiming) TimeAtBeat(beat float64) float64 {
+	if len(t.BpmChanges) == 0 {
+		return 0
+	}
+	return getTimeFromBpmChanges(t.BpmChanges, beat)
+}
+
 // SkobjData は出力データ構造体
 type SkobjData struct {
-	AssetPath string       `json:"asset_path"`
-	Version   string       `json:"version"`
-	Objects   []ScoreFrame `json:"objects"`
+	AssetPath string        `json:"asset_path"`
+	Version   string        `json:"version"`
+	Metadata  SkobjMetadata `json:"metadata"`
+	// Theme はジャケットから抽出したテーマカラー（抽出できなかった場合は省略）
+	Theme   *ThemeColors `json:"theme,omitempty"`
+	Objects []ScoreFrame `json:"objects"`
+}
+
+// SkobjMetadata は譜面と音源のタイミング情報（秒、音源の長さが不明な場合は0）
+type SkobjMetadata struct {
+	FirstNoteTime   float64 `json:"first_note_time"`
+	LastNoteTime    float64 `json:"last_note_time"`
+	MusicDuration   float64 `json:"music_duration"`
+	LeadingSilence  float64 `json:"leading_silence"`
+	TrailingSilence float64 `json:"trailing_silence"`
 }
 
 // getValueFromData はデータ配列から指定された名前の値を取得する
@@ -48,6 +74,41 @@ func getValueFromData(data []map[string]interface{}, name string) float64 {
 	return 0.0
 }
 
+// entityData はエンティティのdata配列を取得する
+func entityData(entity map[string]interface{}) []map[string]interface{} {
+	var dataSlice []map[string]interface{}
+	if data, ok := entity["data"].([]interface{}); ok {
+		for _, d := range data {
+			if dm, ok := d.(map[string]interface{}); ok {
+				dataSlice = append(dataSlice, dm)
+			}
+		}
+	}
+	return dataSlice
+}
+
+// parseBpmChanges はエンティティからBPM変更を抽出し、ビート順に並べて返す
+func parseBpmChanges(entities []interface{}) []BpmChange {
+	var bpmChanges []BpmChange
+	for _, entity := range entities {
+		if entityMap, ok := entity.(map[string]interface{}); ok {
+			if archetype, _ := entityMap["archetype"].(string); archetype != "#BPM_CHANGE" {
+				continue
+			}
+			dataSlice := entityData(entityMap)
+			beat := getValueFromData(dataSlice, "#BEAT")
+			bpm := getValueFromData(dataSlice, "#BPM")
+			if bpm > 0 {
+				bpmChanges = append(bpmChanges, BpmChange{Beat: beat, BPM: bpm})
+			}
+		}
+	}
+	sort.Slice(bpmChanges, func(i, j int) bool {
+		return bpmChanges[i].Beat < bpmChanges[j].Beat
+	})
+	return bpmChanges
+}
+
 // getTimeFromBpmChanges はBPM変更リストから指定されたビート位置の時間を計算する
 func getTimeFromBpmChanges(bpmChanges []BpmChange, beat float64) float64 {
 	var retTime float64
@@ -70,7 +131,7 @@ func getTimeFromBpmChanges(bpmChanges []BpmChange, beat float64) float64 {
 }
 
 // calculateScoreFrames はスコア、コンボ、秒数、ランク、スコアバーのフレームリストを計算する
-func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string]interface{}, power float64) ([]ScoreFrame, float64) {
+func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string]interface{}, power float64) ([]ScoreFrame, ChartTiming) {
 	rating, _ := levelInfo["rating"].(float64)
 	entities, _ := levelData["entities"].([]interface{})
 
@@ -105,32 +166,18 @@ func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string
 		}
 	}
 
+	bpmChanges := parseBpmChanges(entities)
 	if weightedNotesCount == 0 {
-		return []ScoreFrame{{Seconds: 0, Combo: 0, Score: 0, AddScore: 0, Rank: "d", ScoreBar: 0}}, 0
+		return []ScoreFrame{{Seconds: 0, Combo: 0, Score: 0, AddScore: 0, Rank: "d", ScoreBar: 0}}, ChartTiming{BpmChanges: bpmChanges}
 	}
 
-	var bpmChanges []BpmChange
 	var noteEntities []map[string]interface{}
 
-	// エンティティを分類
+	// ノーツのエンティティを抽出
 	for _, entity := range entities {
 		if entityMap, ok := entity.(map[string]interface{}); ok {
 			archetype, _ := entityMap["archetype"].(string)
-			if archetype == "#BPM_CHANGE" {
-				if data, ok := entityMap["data"].([]interface{}); ok {
-					var dataSlice []map[string]interface{}
-					for _, d := range data {
-						if dm, ok := d.(map[string]interface{}); ok {
-							dataSlice = append(dataSlice, dm)
-						}
-					}
-					beat := getValueFromData(dataSlice, "#BEAT")
-					bpm := getValueFromData(dataSlice, "#BPM")
-					if bpm > 0 {
-						bpmChanges = append(bpmChanges, BpmChange{Beat: beat, BPM: bpm})
-					}
-				}
-			} else if weight, exists := config.WeightMap[archetype]; exists && weight > 0 {
+			if weight, exists := config.WeightMap[archetype]; exists && weight > 0 {
 				if _, ok := entityMap["data"].([]interface{}); ok {
 					noteEntities = append(noteEntities, entityMap)
 				}
@@ -138,30 +185,10 @@ func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string
 		}
 	}
 
-	// BPM変更をソート
-	sort.Slice(bpmChanges, func(i, j int) bool {
-		return bpmChanges[i].Beat < bpmChanges[j].Beat
-	})
-
 	// ノーツエンティティをビート順にソート
 	sort.Slice(noteEntities, func(i, j int) bool {
-		var dataI, dataJ []map[string]interface{}
-		if d, ok := noteEntities[i]["data"].([]interface{}); ok {
-			for _, item := range d {
-				if dm, ok := item.(map[string]interface{}); ok {
-					dataI = append(dataI, dm)
-				}
-			}
-		}
-		if d, ok := noteEntities[j]["data"].([]interface{}); ok {
-			for _, item := range d {
-				if dm, ok := item.(map[string]interface{}); ok {
-					dataJ = append(dataJ, dm)
-				}
-			}
-		}
-		beatI := getValueFromData(dataI, "#BEAT")
-		beatJ := getValueFromData(dataJ, "#BEAT")
+		beatI := getValueFromData(entityData(noteEntities[i]), "#BEAT")
+		beatJ := getValueFromData(entityData(noteEntities[j]), "#BEAT")
 		return beatI < beatJ
 	})
 
@@ -169,7 +196,7 @@ func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string
 	levelFax := (rating-5)*0.005 + 1
 	comboFax := 1.0
 	score := 0.0
-	var lastNoteTime float64
+	timing := ChartTiming{BpmChanges: bpmChanges}
 
 	for i, entity := range noteEntities {
 		comboCounter := i + 1
@@ -184,21 +211,17 @@ func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string
 		archetype, _ := entity["archetype"].(string)
 		weight := config.WeightMap[archetype]
 
-		var dataSlice []map[string]interface{}
-		if data, ok := entity["data"].([]interface{}); ok {
-			for _, d := range data {
-				if dm, ok := d.(map[string]interface{}); ok {
-					dataSlice = append(dataSlice, dm)
-				}
-			}
-		}
+		dataSlice := entityData(entity)
 
 		addScore := (power / weightedNotesCount) * 4 * weight * 1 * levelFax * comboFax * 1
 		score += addScore
 
 		beat := getValueFromData(dataSlice, "#BEAT")
 		time := getTimeFromBpmChanges(bpmChanges, beat)
-		lastNoteTime = time
+		if i == 0 {
+			timing.FirstNoteTime = time
+		}
+		timing.LastNoteTime = time
 
 		// ランクとスコアバーを計算
 		rank := ""
@@ -240,66 +263,78 @@ func calculateScoreFrames(levelInfo map[string]interface{}, levelData map[string
 		})
 	}
 
-	return frames, lastNoteTime
+	return frames, timing
 }
 
 // GenerateSkobjData は譜面データを読み込み、スコアオブジェクトデータを計算してJSONファイルに出力する
-func GenerateSkobjData(levelID, distDir string, teamPower float64, appVersion string) (float64, error) {
+// 音源の長さが分かる場合は、音源の終了後にノーツがあると警告する
+func GenerateSkobjData(levelID, distDir string, teamPower float64, appVersion string, audio AudioInfo, theme *ThemeColors) (ChartTiming, error) {
 	levelInfoPath := filepath.Join(distDir, "level.json")
 	chartPath := filepath.Join(distDir, "chart.json")
 
 	// ファイルを読み込み
 	levelInfoFile, err := os.Open(levelInfoPath)
 	if err != nil {
-		return 0, fmt.Errorf("level.jsonの読み込みに失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("level.jsonの読み込みに失敗しました: %w", err)
 	}
 	defer levelInfoFile.Close()
 
 	var levelInfoData map[string]interface{}
 	if err := json.NewDecoder(levelInfoFile).Decode(&levelInfoData); err != nil {
-		return 0, fmt.Errorf("level.jsonの解析に失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("level.jsonの解析に失敗しました: %w", err)
 	}
 
 	levelInfo, ok := levelInfoData["item"].(map[string]interface{})
 	if !ok {
-		return 0, fmt.Errorf("level.jsonにitemフィールドが見つかりません")
+		return ChartTiming{}, fmt.Errorf("level.jsonにitemフィールドが見つかりません")
 	}
 
 	chartFile, err := os.Open(chartPath)
 	if err != nil {
-		return 0, fmt.Errorf("chart.jsonの読み込みに失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("chart.jsonの読み込みに失敗しました: %w", err)
 	}
 	defer chartFile.Close()
 
 	var levelData map[string]interface{}
 	if err := json.NewDecoder(chartFile).Decode(&levelData); err != nil {
-		return 0, fmt.Errorf("chart.jsonの解析に失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("chart.jsonの解析に失敗しました: %w", err)
 	}
 
 	fmt.Println("スコアオブジェクトデータの生成を開始します...")
 
-	scoreFrames, lastNoteTime := calculateScoreFrames(levelInfo, levelData, teamPower)
-	assetsFullPath := strings.Replace(filepath.ToSlash(filepath.Join(utils.GetAppRoot(), "assets")), "/", "\\", -1) + "\\"
+	scoreFrames, timing := calculateScoreFrames(levelInfo, levelData, teamPower)
+	if audio.Duration > 0 && timing.LastNoteTime > audio.Duration {
+		fmt.Printf("警告: 最後のノーツ (%.3f秒) が音源の終了 (%.3f秒) より後にあります。\n", timing.LastNoteTime, audio.Duration)
+	}
+	assetsFullPath := utils.WindowsPath(filepath.Join(utils.GetAppRoot(), "assets")) + "\\"
 
 	outputData := SkobjData{
 		AssetPath: assetsFullPath,
 		Version:   appVersion,
-		Objects:   scoreFrames,
+		Metadata: SkobjMetadata{
+			FirstNoteTime:   timing.FirstNoteTime,
+			LastNoteTime:    timing.LastNoteTime,
+			MusicDuration:   audio.Duration,
+			LeadingSilence:  audio.LeadingSilence,
+			TrailingSilence: audio.TrailingSilence,
+		},
+		Theme:   theme,
+		Objects: scoreFrames,
 	}
 
 	outputPath := filepath.Join(distDir, "skobj_data.json")
 	outputFile, err := os.Create(outputPath)
 	if err != nil {
-		return 0, fmt.Errorf("出力ファイルの作成に失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("出力ファイルの作成に失敗しました: %w", err)
 	}
 	defer outputFile.Close()
 
 	encoder := json.NewEncoder(outputFile)
 	encoder.SetIndent("", "    ")
 	if err := encoder.Encode(outputData); err != nil {
-		return 0, fmt.Errorf("JSON出力に失敗しました: %w", err)
+		return ChartTiming{}, fmt.Errorf("JSON出力に失敗しました: %w", err)
 	}
 
 	fmt.Printf("スコアオブジェクトデータを '%s' に保存しました。\n", outputPath)
-	return lastNoteTime, nil
+	return timing, nil
 }
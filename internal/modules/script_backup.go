@@ -0,0 +1,320 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sekai-overlay-go/internal/config"
+)
+
+// セットアップでスクリプトディレクトリにインストールするファイル
+const (
+	objScriptFile = "@SekaiObjects.obj2"
+	unmultAnmFile = "unmult.anm2"
+	dkjsonLuaFile = "dkjson.lua"
+)
+
+// installedScriptFiles はセットアップでインストールするすべてのファイル
+var installedScriptFiles = []string{objScriptFile, unmultAnmFile, dkjsonLuaFile}
+
+const (
+	// backupInfoFile はバックアップの内容を記録するファイル名
+	backupInfoFile = "backup.json"
+	// maxScriptBackups は残しておくバックアップの数（古いものから削除する）
+	maxScriptBackups = 5
+	// backupTimeFormat はバックアップのディレクトリ名に使う作成日時の形式
+	backupTimeFormat = "20060102-150405"
+	// rolledBackKey はロールバックしたことを記録するconfig.iniのキー
+	// 記録がある間はセットアップで確認するまでスクリプトを再インストールしない
+	rolledBackKey = "RolledBack"
+)
+
+// scriptBackup はインストール・アンインストール前のスクリプトと設定の状態
+type scriptBackup struct {
+	CreatedAt string `json:"created_at"`
+	// Reason はバックアップを作成した操作（setup/uninstall）
+	Reason    string `json:"reason"`
+	ScriptDir string `json:"script_dir"`
+	// LastVersion・SetupCompleteはバックアップ時のconfig.iniの値（未設定の場合は空）
+	LastVersion   string             `json:"last_version"`
+	SetupComplete string             `json:"setup_complete"`
+	Files         []scriptBackupFile `json:"files"`
+
+	dir string
+	// createdAt・seq はディレクトリ名から読み取った作成日時と、同じ秒に作成した場合の連番
+	createdAt time.Time
+	seq       int
+}
+
+// scriptBackupFile はバックアップしたファイルの情報
+type scriptBackupFile struct {
+	Name string `json:"name"`
+	// Exists がfalseの場合はバックアップ時にファイルがなかったので、ロールバックで削除する
+	Exists bool `json:"exists"`
+	// Version は@SekaiObjects.obj2のSKOBJ_VERSION（読み取れない場合は空）
+	Version string `json:"version,omitempty"`
+}
+
+// scriptBackupRoot はバックアップを保存するディレクトリ
+func scriptBackupRoot() string {
+	return filepath.Join(config.GetConfigDir(), "backups")
+}
+
+// backupScripts はスクリプトディレクトリのファイルと設定の状態を設定ディレクトリにバックアップする
+// 対象のファイルが1つもない場合は、復元するものがないのでバックアップしない
+func backupScripts(reason string, names []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	existing := 0
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(config.AviUtlScriptDir, name)); err == nil {
+			existing++
+		}
+	}
+	if existing == 0 {
+		fmt.Println("バックアップするスクリプトがないため、バックアップをスキップします。")
+		return nil
+	}
+
+	now := time.Now()
+	dir := filepath.Join(scriptBackupRoot(), now.Format(backupTimeFormat))
+	for i := 2; dirExists(dir); i++ {
+		dir = filepath.Join(scriptBackupRoot(), fmt.Sprintf("%s-%d", now.Format(backupTimeFormat), i))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("バックアップディレクトリの作成に失敗しました: %w", err)
+	}
+
+	backup := scriptBackup{
+		CreatedAt:     now.Format(time.RFC3339),
+		Reason:        reason,
+		ScriptDir:     config.AviUtlScriptDir,
+		LastVersion:   cfg.Section("AppInfo").Key("LastVersion").String(),
+		SetupComplete: cfg.Section("AppInfo").Key("SetupComplete").String(),
+	}
+	for _, name := range names {
+		file := scriptBackupFile{Name: name}
+		srcPath := filepath.Join(config.AviUtlScriptDir, name)
+		if _, err := os.Stat(srcPath); err == nil {
+			if err := copyFile(srcPath, filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("'%s' のバックアップに失敗しました: %w", name, err)
+			}
+			file.Exists = true
+			if name == objScriptFile {
+				file.Version = scriptVersion(srcPath)
+			}
+		}
+		backup.Files = append(backup.Files, file)
+	}
+
+	data, err := json.MarshalIndent(backup, "", "    ")
+	if err != nil {
+		return fmt.Errorf("バックアップ情報の作成に失敗しました: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupInfoFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("バックアップ情報の書き込みに失敗しました: %w", err)
+	}
+	fmt.Printf("現在のスクリプトを '%s' にバックアップしました。\n", dir)
+
+	pruneScriptBackups()
+	return nil
+}
+
+// listScriptBackups はバックアップを新しい順に返す
+func listScriptBackups() ([]*scriptBackup, error) {
+	entries, err := os.ReadDir(scriptBackupRoot())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("バックアップディレクトリの読み込みに失敗しました: %w", err)
+	}
+
+	var backups []*scriptBackup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		createdAt, seq, ok := parseBackupDirName(entry.Name())
+		if !ok {
+			continue
+		}
+		dir := filepath.Join(scriptBackupRoot(), entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, backupInfoFile))
+		if err != nil {
+			continue
+		}
+		backup := &scriptBackup{dir: dir, createdAt: createdAt, seq: seq}
+		if err := json.Unmarshal(data, backup); err != nil {
+			fmt.Printf("警告: バックアップ情報 '%s' の解析に失敗しました: %v\n", dir, err)
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	// 名前の順では-10が-2より前になるため、作成日時と連番で並べる
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].createdAt.Equal(backups[j].createdAt) {
+			return backups[i].createdAt.After(backups[j].createdAt)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+// parseBackupDirName はバックアップのディレクトリ名（20060102-150405、20060102-150405-2など）から
+// 作成日時と連番（連番がない場合は1）を読み取る
+func parseBackupDirName(name string) (time.Time, int, bool) {
+	if len(name) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	createdAt, err := time.ParseInLocation(backupTimeFormat, name[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	suffix := name[len(backupTimeFormat):]
+	if suffix == "" {
+		return createdAt, 1, true
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+	if err != nil || !strings.HasPrefix(suffix, "-") || seq < 2 {
+		return time.Time{}, 0, false
+	}
+	return createdAt, seq, true
+}
+
+// pruneScriptBackups は古いバックアップを削除する
+func pruneScriptBackups() {
+	backups, err := listScriptBackups()
+	if err != nil || len(backups) <= maxScriptBackups {
+		return
+	}
+	for _, backup := range backups[maxScriptBackups:] {
+		os.RemoveAll(backup.dir)
+	}
+}
+
+// UninstallScripts はインストールしたスクリプトを削除し、config.iniのセットアップの記録を消す
+// 削除前のファイルはバックアップするので、RollbackScriptsで元に戻せる
+func UninstallScripts() error {
+	if err := backupScripts("uninstall", installedScriptFiles); err != nil {
+		return err
+	}
+
+	for _, name := range installedScriptFiles {
+		path := filepath.Join(config.AviUtlScriptDir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("'%s' の削除に失敗しました: %w", path, err)
+		}
+		fmt.Printf("'%s' を削除しました。\n", path)
+	}
+
+	for _, key := range []string{"LastVersion", "SetupComplete", rolledBackKey} {
+		if err := setConfigValue(key, ""); err != nil {
+			return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+		}
+	}
+	return nil
+}
+
+// RollbackScripts は最新のバックアップからスクリプトと設定を復元し、使用したバックアップを削除する
+// バックアップ時になかったファイルは削除するので、セットアップ前の状態に戻る
+// 次のセットアップで元に戻らないように、ロールバックしたことをconfig.iniに記録する
+func RollbackScripts() error {
+	backups, err := listScriptBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("復元できるバックアップがありません")
+	}
+	backup := backups[0]
+
+	fmt.Printf("%s のバックアップ (%s) から復元します...\n", backup.CreatedAt, backup.Reason)
+	if backup.ScriptDir != config.AviUtlScriptDir {
+		fmt.Printf("注意: バックアップ時のスクリプトディレクトリ '%s' に復元します。\n", backup.ScriptDir)
+	}
+	if err := os.MkdirAll(backup.ScriptDir, 0755); err != nil {
+		return fmt.Errorf("スクリプトディレクトリの作成に失敗しました: %w", err)
+	}
+
+	for _, file := range backup.Files {
+		destPath := filepath.Join(backup.ScriptDir, file.Name)
+		if !file.Exists {
+			if err := removeIfExists(destPath); err != nil {
+				return fmt.Errorf("'%s' の削除に失敗しました: %w", destPath, err)
+			}
+			continue
+		}
+		if err := copyFile(filepath.Join(backup.dir, file.Name), destPath); err != nil {
+			return fmt.Errorf("'%s' の復元に失敗しました: %w", destPath, err)
+		}
+		if file.Version != "" {
+			fmt.Printf("'%s' (%s) を復元しました。\n", destPath, file.Version)
+		} else {
+			fmt.Printf("'%s' を復元しました。\n", destPath)
+		}
+	}
+
+	if err := setConfigValue("LastVersion", backup.LastVersion); err != nil {
+		return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+	}
+	if err := setConfigValue("SetupComplete", backup.SetupComplete); err != nil {
+		return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+	}
+	if err := setConfigValue(rolledBackKey, backup.CreatedAt); err != nil {
+		return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+	}
+
+	if err := os.RemoveAll(backup.dir); err != nil {
+		return fmt.Errorf("使用したバックアップの削除に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// ScriptsRolledBack はロールバックした後、まだセットアップで再インストールしていないかを返す
+func ScriptsRolledBack() bool {
+	cfg, err := loadConfig()
+	if err != nil {
+		return false
+	}
+	return cfg.Section("AppInfo").Key(rolledBackKey).String() != ""
+}
+
+// ClearScriptRollback はロールバックの記録を消し、次のセットアップでスクリプトを再インストールできるようにする
+func ClearScriptRollback() error {
+	if err := setConfigValue(rolledBackKey, ""); err != nil {
+		return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// scriptVersion は@SekaiObjects.obj2のSKOBJ_VERSIONを読み取る（読み取れない場合は空）
+func scriptVersion(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, ln := range strings.Split(string(content), "\n") {
+		if strings.Contains(ln, "SKOBJ_VERSION") {
+			// 例: SKOBJ_VERSION = "v0.1.0"
+			parts := strings.SplitN(ln, "=", 2)
+			if len(parts) == 2 {
+				return strings.Trim(strings.TrimSpace(parts[1]), " \"'")
+			}
+			break
+		}
+	}
+	return ""
+}
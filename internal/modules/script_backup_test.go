@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sekai-overlay-go/internal/config"
+)
+
+// useTempScriptEnv は設定ディレクトリとスクリプトディレクトリを一時ディレクトリに切り替え、スクリプトディレクトリを返す
+func useTempScriptEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	scriptDir := t.TempDir()
+	saved := config.AviUtlScriptDir
+	config.AviUtlScriptDir = scriptDir
+	t.Cleanup(func() { config.AviUtlScriptDir = saved })
+	return scriptDir
+}
+
+func TestParseBackupDirName(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	for _, tc := range []struct {
+		name string
+		seq  int
+		ok   bool
+	}{
+		{"20260102-030405", 1, true},
+		{"20260102-030405-2", 2, true},
+		{"20260102-030405-10", 10, true},
+		{"20260102-030405-1", 0, false},
+		{"20260102-030405x", 0, false},
+		{"backup", 0, false},
+	} {
+		createdAt, seq, ok := parseBackupDirName(tc.name)
+		if ok != tc.ok || seq != tc.seq || (ok && !createdAt.Equal(base)) {
+			t.Errorf("parseBackupDirName(%q) = %v, %d, %v", tc.name, createdAt, seq, ok)
+		}
+	}
+}
+
+func TestListScriptBackupsOrder(t *testing.T) {
+	useTempScriptEnv(t)
+	names := []string{"20260101-115959", "20260101-120000", "20260101-120000-2", "20260101-120000-10", "unknown"}
+	for _, name := range names {
+		dir := filepath.Join(scriptBackupRoot(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, backupInfoFile), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := listScriptBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20260101-120000-10", "20260101-120000-2", "20260101-120000", "20260101-115959"}
+	if len(backups) != len(want) {
+		t.Fatalf("バックアップの数 = %d, want %d", len(backups), len(want))
+	}
+	for i, backup := range backups {
+		if got := filepath.Base(backup.dir); got != want[i] {
+			t.Errorf("%d番目 = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestBackupScriptsSkipsEmpty(t *testing.T) {
+	useTempScriptEnv(t)
+	if err := backupScripts("setup", installedScriptFiles); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := listScriptBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("スクリプトがないのにバックアップが作成されました: %d", len(backups))
+	}
+}
+
+func TestRollbackIsNotUndoneBySetup(t *testing.T) {
+	scriptDir := useTempScriptEnv(t)
+	objPath := filepath.Join(scriptDir, objScriptFile)
+	if err := os.WriteFile(objPath, []byte(`SKOBJ_VERSION = "v0.0.1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{"LastVersion": "v0.0.1", "SetupComplete": "true"} {
+		if err := updateConfigFile(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// セットアップで新しいバージョンに置き換えた後、ロールバックする
+	if err := backupScripts("setup", []string{objScriptFile}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(objPath, []byte(`SKOBJ_VERSION = "v9.9.9"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RollbackScripts(); err != nil {
+		t.Fatal(err)
+	}
+	if !ScriptsRolledBack() {
+		t.Fatal("ロールバックが記録されていません")
+	}
+
+	if err := CheckAndRunSetup(); err != nil {
+		t.Fatal(err)
+	}
+	if got := scriptVersion(objPath); got != "v0.0.1" {
+		t.Errorf("セットアップでロールバックが元に戻りました: %s", got)
+	}
+
+	if err := ClearScriptRollback(); err != nil {
+		t.Fatal(err)
+	}
+	if ScriptsRolledBack() {
+		t.Error("ロールバックの記録が消えていません")
+	}
+}
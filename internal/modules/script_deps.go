@@ -0,0 +1,82 @@
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sekai-overlay-go/internal/config"
+	"sekai-overlay-go/internal/utils"
+)
+
+// scriptDependency はセットアップでインストールする外部のスクリプト
+type scriptDependency struct {
+	Name string
+	URL  string
+	// SHA256 は固定したチェックサム（16進数）
+	// 空の場合はダウンロードしたファイルを警告付きで使い、同梱のファイルは使わない
+	SHA256 string
+	// Bundled はダウンロードできない場合に使う同梱のファイルのパス
+	Bundled string
+}
+
+// scriptDependencies はunmult.anm2とdkjson.luaの取得元とチェックサム
+func scriptDependencies() []scriptDependency {
+	return []scriptDependency{
+		{Name: unmultAnmFile, URL: config.UnmultAnmURL, SHA256: config.UnmultAnmSHA256, Bundled: bundledScriptPath(unmultAnmFile)},
+		{Name: dkjsonLuaFile, URL: config.DkjsonLuaURL, SHA256: config.DkjsonLuaSHA256, Bundled: bundledScriptPath(dkjsonLuaFile)},
+	}
+}
+
+// bundledScriptPath は同梱したスクリプトのパス
+func bundledScriptPath(name string) string {
+	return utils.ResourcePath(filepath.Join("assets", "scripts", "vendor", name))
+}
+
+// fetchDependency はスクリプトをダウンロードしてチェックサムを検証し、取得元の説明とともに返す
+// ダウンロードに失敗した場合やチェックサムが一致しない場合は、検証できた同梱のファイルを使う
+// チェックサムが固定されていない場合は、ダウンロードしたファイルを実際のSHA-256を表示して使う
+func fetchDependency(dep scriptDependency) ([]byte, string, error) {
+	data, downloadErr := downloadSetup(dep.URL)
+	if downloadErr == nil {
+		if dep.SHA256 == "" {
+			fmt.Printf("警告: %sのSHA-256が固定されていないため、ダウンロードしたファイルを検証せずに使います (sha256: %s)\n", dep.Name, sha256Hex(data))
+			return data, "ダウンロード (SHA-256未検証)", nil
+		}
+		if downloadErr = verifyChecksum(dep, data); downloadErr == nil {
+			return data, "ダウンロード (SHA-256検証済み)", nil
+		}
+	}
+	fmt.Printf("警告: %sのダウンロードに失敗したため、同梱のファイルを使います: %v\n", dep.Name, downloadErr)
+
+	data, err := os.ReadFile(dep.Bundled)
+	if err != nil {
+		return nil, "", fmt.Errorf("ダウンロードに失敗し、同梱のファイルも読み込めませんでした: %w", err)
+	}
+	if err := verifyChecksum(dep, data); err != nil {
+		return nil, "", fmt.Errorf("同梱のファイルの検証に失敗しました: %w", err)
+	}
+	return data, "同梱のファイル (SHA-256検証済み)", nil
+}
+
+// verifyChecksum はデータのSHA-256が固定した値と一致するか確認する
+// チェックサムが固定されていない場合も検証できないのでエラーにし、固定するための値を含める
+func verifyChecksum(dep scriptDependency, data []byte) error {
+	actual := sha256Hex(data)
+	if dep.SHA256 == "" {
+		return fmt.Errorf("%sのSHA-256が固定されていないため検証できません (sha256: %s)", dep.Name, actual)
+	}
+	if !strings.EqualFold(actual, dep.SHA256) {
+		return fmt.Errorf("%sのSHA-256が一致しません (期待値: %s, 実際: %s)", dep.Name, dep.SHA256, actual)
+	}
+	return nil
+}
+
+// sha256Hex はデータのSHA-256を16進数の文字列で返す
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
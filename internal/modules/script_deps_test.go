@@ -0,0 +1,98 @@
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("return {}\n")
+	sum := sha256.Sum256(data)
+	pin := hex.EncodeToString(sum[:])
+
+	for _, tc := range []struct {
+		name    string
+		sha256  string
+		wantErr bool
+	}{
+		{"一致", pin, false},
+		{"大文字", strings.ToUpper(pin), false},
+		{"不一致", strings.Repeat("0", 64), true},
+		{"未固定", "", true},
+	} {
+		err := verifyChecksum(scriptDependency{Name: dkjsonLuaFile, SHA256: tc.sha256}, data)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+		// 未固定の場合も、固定するための値をエラーに含める
+		if tc.sha256 == "" && err != nil && !strings.Contains(err.Error(), pin) {
+			t.Errorf("%s: エラーにSHA-256が含まれていません: %v", tc.name, err)
+		}
+	}
+}
+
+func TestFetchDependency(t *testing.T) {
+	good := []byte("return {}\n")
+	tampered := []byte("os.execute('calc')\n")
+	sum := sha256.Sum256(good)
+	pin := hex.EncodeToString(sum[:])
+
+	served := good
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(served)
+	}))
+	defer server.Close()
+
+	// 閉じたサーバーのURLはオフラインの代わりにする
+	offline := httptest.NewServer(http.NotFoundHandler())
+	offlineURL := offline.URL
+	offline.Close()
+
+	bundled := filepath.Join(t.TempDir(), dkjsonLuaFile)
+	if err := os.WriteFile(bundled, good, 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), dkjsonLuaFile)
+
+	for _, tc := range []struct {
+		name       string
+		served     []byte
+		url        string
+		sha256     string
+		bundled    string
+		wantErr    bool
+		wantSource string
+	}{
+		{"ダウンロード", good, server.URL, pin, missing, false, "ダウンロード"},
+		{"改ざん", tampered, server.URL, pin, bundled, false, "同梱"},
+		{"改ざん・同梱なし", tampered, server.URL, pin, missing, true, ""},
+		{"オフライン", good, offlineURL, pin, bundled, false, "同梱"},
+		{"オフライン・同梱なし", good, offlineURL, pin, missing, true, ""},
+		// 未固定の場合はダウンロードしたファイルを使い、同梱のファイルは検証できないので使わない
+		{"未固定", good, server.URL, "", bundled, false, "未検証"},
+		{"未固定・オフライン", good, offlineURL, "", bundled, true, ""},
+	} {
+		served = tc.served
+		dep := scriptDependency{Name: dkjsonLuaFile, URL: tc.url, SHA256: tc.sha256, Bundled: tc.bundled}
+		data, source, err := fetchDependency(dep)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		if string(data) != string(good) {
+			t.Errorf("%s: data = %q", tc.name, data)
+		}
+		if !strings.Contains(source, tc.wantSource) {
+			t.Errorf("%s: source = %q, want %q", tc.name, source, tc.wantSource)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sekai-overlay-go/internal/config"
+	"sekai-overlay-go/internal/utils"
+)
+
+// ScriptDirCandidate はAviUtl2のスクリプトディレクトリの候補
+type ScriptDirCandidate struct {
+	Path  string
+	Label string
+	// Exists はディレクトリ（ポータブル版の場合はdataフォルダ）が存在するか
+	Exists bool
+}
+
+// scriptDirKey はconfig.iniに保存するスクリプトディレクトリのキー
+const scriptDirKey = "ScriptDir"
+
+// DetectScriptDirs はスクリプトディレクトリの候補を優先度の高い順に返す
+// config.iniで指定されたパス、実行ファイルの近くにあるポータブル版、ProgramDataの標準のパスの順
+// Windows以外ではWineプレフィックスのdrive_c内を探す
+func DetectScriptDirs() []ScriptDirCandidate {
+	var candidates []ScriptDirCandidate
+	seen := map[string]bool{}
+	add := func(path, label string, exists bool) {
+		key := filepath.Clean(path)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		candidates = append(candidates, ScriptDirCandidate{Path: path, Label: label, Exists: exists})
+	}
+
+	if saved := SavedScriptDir(); saved != "" {
+		add(saved, "設定ファイルで指定", dirExists(saved))
+	}
+
+	// ポータブル版はaviutl2.exeと同じフォルダのdata\Scriptを使う
+	appRoot := utils.GetAppRoot()
+	portableDirs := []string{appRoot, filepath.Dir(appRoot), filepath.Join(appRoot, "aviutl2"), filepath.Join(appRoot, "AviUtl2")}
+	standardLabel := "標準 (ProgramData)"
+	if prefix := utils.WinePrefix(); prefix != "" {
+		driveC := filepath.Join(prefix, "drive_c")
+		portableDirs = append(portableDirs, filepath.Join(driveC, "aviutl2"), filepath.Join(driveC, "AviUtl2"))
+		standardLabel = "Wine (ProgramData)"
+	}
+	for _, dir := range portableDirs {
+		if _, err := os.Stat(filepath.Join(dir, "aviutl2.exe")); err != nil {
+			continue
+		}
+		dataDir := filepath.Join(dir, "data")
+		if dirExists(dataDir) {
+			add(filepath.Join(dataDir, "Script"), "ポータブル版", true)
+		}
+	}
+
+	add(standardScriptDir(), standardLabel, dirExists(filepath.Dir(standardScriptDir())))
+	return candidates
+}
+
+// ResolveScriptDir は使用するスクリプトディレクトリを決定し、config.AviUtlScriptDirに設定する
+// 設定ファイルで指定されたパスは存在しなくても優先し、それ以外は存在する候補を使う
+func ResolveScriptDir() ScriptDirCandidate {
+	candidates := DetectScriptDirs()
+	selected := candidates[len(candidates)-1]
+	for _, candidate := range candidates {
+		if candidate.Exists || candidate.Path == SavedScriptDir() {
+			selected = candidate
+			break
+		}
+	}
+	config.AviUtlScriptDir = selected.Path
+	return selected
+}
+
+// SavedScriptDir はconfig.iniに保存されたスクリプトディレクトリを返す（未設定の場合は空）
+func SavedScriptDir() string {
+	cfg, err := loadConfig()
+	if err != nil {
+		return ""
+	}
+	return cfg.Section("AppInfo").Key(scriptDirKey).String()
+}
+
+// SaveScriptDir はスクリプトディレクトリをconfig.iniに保存し、以降の処理で使用する
+func SaveScriptDir(path string) error {
+	if err := updateConfigFile(scriptDirKey, path); err != nil {
+		return fmt.Errorf("スクリプトディレクトリの保存に失敗しました: %w", err)
+	}
+	config.AviUtlScriptDir = path
+	return nil
+}
+
+// CheckScriptDir はスクリプトディレクトリに書き込めるか確認する
+func CheckScriptDir(path string) error {
+	if !checkWritePermission(path) {
+		return fmt.Errorf("'%s' に書き込めません。管理者権限で実行するか、別のディレクトリを指定してください", path)
+	}
+	return nil
+}
+
+// standardScriptDir はAviUtl2のインストーラー版が使うスクリプトディレクトリ
+// Windows以外ではWineプレフィックス内のProgramDataを使う
+func standardScriptDir() string {
+	if prefix := utils.WinePrefix(); prefix != "" {
+		return filepath.Join(prefix, "drive_c", "ProgramData", "aviutl2", "Script")
+	}
+	if programData := os.Getenv("ProgramData"); programData != "" {
+		return filepath.Join(programData, "aviutl2", "Script")
+	}
+	return `C:\ProgramData\aviutl2\Script`
+}
+
+// dirExists はディレクトリが存在するか確認する
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
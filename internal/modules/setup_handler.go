@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,17 +18,22 @@ import (
 )
 
 // CheckAndRunSetup は設定をチェックし、必要な場合はセットアップを実行する
+// ロールバックした後はClearScriptRollbackで記録を消すまで何もしない
 func CheckAndRunSetup() error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
 	}
+	if rolledBack := cfg.Section("AppInfo").Key(rolledBackKey).String(); rolledBack != "" {
+		fmt.Printf("%s のバックアップにロールバックしているため、スクリプトを更新しません。\n", rolledBack)
+		return nil
+	}
 
 	storedVersion := cfg.Section("AppInfo").Key("LastVersion").String()
 	setupComplete := cfg.Section("AppInfo").Key("SetupComplete").String() == "true"
 
 	tasks := []string{}
-	if storedVersion != config.AppVersion || !setupComplete {
+	if storedVersion != config.AppVersion || !setupComplete || objScriptOutdated() {
 		tasks = append(tasks, "update_obj")
 	}
 	if !setupComplete {
@@ -40,6 +46,20 @@ func CheckAndRunSetup() error {
 
 	fmt.Println("セットアップを開始します...")
 
+	// 上書きするファイルをバックアップしておく（rollbackで元に戻せる）
+	backupFiles := []string{}
+	for _, task := range tasks {
+		switch task {
+		case "update_obj":
+			backupFiles = append(backupFiles, objScriptFile)
+		case "install_anm":
+			backupFiles = append(backupFiles, unmultAnmFile, dkjsonLuaFile)
+		}
+	}
+	if err := backupScripts("setup", backupFiles); err != nil {
+		return fmt.Errorf("スクリプトのバックアップに失敗しました: %w", err)
+	}
+
 	successMessages := []string{}
 
 	for _, task := range tasks {
@@ -54,13 +74,15 @@ func CheckAndRunSetup() error {
 			successMessages = append(successMessages, "・'@SekaiObjects.obj2' をインストール/更新しました。")
 
 		case "install_anm":
-			if err := installAnmScript(); err != nil {
+			sources, err := installAnmScript()
+			if err != nil {
 				return fmt.Errorf("ANMスクリプトのインストールに失敗しました: %w", err)
 			}
 			if err := updateConfigFile("SetupComplete", "true"); err != nil {
 				return fmt.Errorf("設定ファイルの更新に失敗しました: %w", err)
 			}
-			successMessages = append(successMessages, "・'unmult.anm2', 'dkjson.lua' をインストールしました。")
+			successMessages = append(successMessages, "・'unmult.anm2', 'dkjson.lua' をインストールしました。取得元:")
+			successMessages = append(successMessages, sources...)
 		}
 	}
 
@@ -113,28 +135,12 @@ func CheckAndNotifyUpdates(console *ui.Console) error {
 	}
 
 	// @SekaiObjects.obj2 のインストール済みバージョンを確認
-	objPath := filepath.Join(config.AviUtlScriptDir, "@SekaiObjects.obj2")
-	installedObjVer := ""
-	if _, err := os.Stat(objPath); err == nil {
-		content, err := os.ReadFile(objPath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, ln := range lines {
-				if strings.Contains(ln, "SKOBJ_VERSION") {
-					// 例: SKOBJ_VERSION = "v0.1.0"
-					parts := strings.SplitN(ln, "=", 2)
-					if len(parts) == 2 {
-						installedObjVer = strings.TrimSpace(parts[1])
-						installedObjVer = strings.Trim(installedObjVer, " \"'")
-					}
-					break
-				}
-			}
-		}
-	}
+	installedObjVer := scriptVersion(filepath.Join(config.AviUtlScriptDir, objScriptFile))
 
 	if installedObjVer == "" {
 		console.PrintInfo("@SekaiObjects.obj2 が見つからないか、バージョン情報が読み取れませんでした。手動でセットアップを実行してください。")
+	} else if installedObjVer != latestTag && ScriptsRolledBack() {
+		console.PrintInfo(fmt.Sprintf("@SekaiObjects.obj2 はロールバックしたバージョンです (installed: %s, latest: %s)。", installedObjVer, latestTag))
 	} else if installedObjVer != latestTag {
 		console.PrintInfo(fmt.Sprintf("@SekaiObjects.obj2 が最新ではありません (installed: %s, latest: %s)。自動で置き換えは行いません。必要ならメニューのセットアップを実行してください。", installedObjVer, latestTag))
 	} else {
@@ -171,6 +177,23 @@ func updateConfigFile(key, value string) error {
 	return cfg.SaveTo(config.GetConfigPath())
 }
 
+// setConfigValue は設定ファイルの値を更新する（空の場合はキーを削除する）
+func setConfigValue(key, value string) error {
+	if value != "" {
+		return updateConfigFile(key, value)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Section("AppInfo").HasKey(key) {
+		return nil
+	}
+	cfg.Section("AppInfo").DeleteKey(key)
+	return cfg.SaveTo(config.GetConfigPath())
+}
+
 // checkWritePermission は指定されたパスへの書き込み権限があるかチェックする
 func checkWritePermission(path string) bool {
 	if err := os.MkdirAll(path, 0755); err != nil {
@@ -186,14 +209,13 @@ func checkWritePermission(path string) bool {
 	return true
 }
 
-// installObjScript は@SekaiObjects.obj2をインストールする
-func installObjScript() error {
+// objScriptContent はインストールする@SekaiObjects.obj2の内容を返す（バージョンを書き換える）
+func objScriptContent() ([]byte, error) {
 	srcPath := utils.ResourcePath("assets/scripts/@SekaiObjects.obj2")
-	destPath := filepath.Join(config.AviUtlScriptDir, "@SekaiObjects.obj2")
 
 	content, err := os.ReadFile(srcPath)
 	if err != nil {
-		return fmt.Errorf("ソースファイルの読み込みに失敗しました: %w", err)
+		return nil, fmt.Errorf("ソースファイルの読み込みに失敗しました: %w", err)
 	}
 
 	lines := strings.Split(string(content), "\n")
@@ -203,8 +225,29 @@ func installObjScript() error {
 		lines[8] = fmt.Sprintf("SKOBJ_VERSION = \"%s\"\n", config.AppVersion)
 	}
 
-	newContent := strings.Join(lines, "\n")
-	if err := os.WriteFile(destPath, []byte(newContent), 0644); err != nil {
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// objScriptOutdated はインストール済みの@SekaiObjects.obj2が同梱のものと異なるか確認する
+// バージョンが同じでもスクリプトの内容が変わっている場合は再インストールする
+func objScriptOutdated() bool {
+	expected, err := objScriptContent()
+	if err != nil {
+		return false
+	}
+	installed, err := os.ReadFile(filepath.Join(config.AviUtlScriptDir, objScriptFile))
+	return err != nil || !bytes.Equal(installed, expected)
+}
+
+// installObjScript は@SekaiObjects.obj2をインストールする
+func installObjScript() error {
+	destPath := filepath.Join(config.AviUtlScriptDir, objScriptFile)
+
+	newContent, err := objScriptContent()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(destPath, newContent, 0644); err != nil {
 		return fmt.Errorf("ファイルの書き込みに失敗しました: %w", err)
 	}
 
@@ -212,42 +255,45 @@ func installObjScript() error {
 	return nil
 }
 
-// installAnmScript はunmult.anm2, dkjson.luaをダウンロードしてインストールする
-func installAnmScript() error {
-	// unmult.anm2のダウンロード
-	destPath := filepath.Join(config.AviUtlScriptDir, "unmult.anm2")
-	if err := downloadFileSetup(config.UnmultAnmURL, destPath); err != nil {
-		return fmt.Errorf("unmult.anm2のダウンロードに失敗しました: %w", err)
+// installAnmScript はunmult.anm2, dkjson.luaを取得・検証してインストールし、取得元の一覧を返す
+// すべてのファイルの取得に成功してから書き込むので、途中で失敗しても既存のファイルは変わらない
+func installAnmScript() ([]string, error) {
+	type fetched struct {
+		name, source string
+		data         []byte
+	}
+	var files []fetched
+	for _, dep := range scriptDependencies() {
+		data, source, err := fetchDependency(dep)
+		if err != nil {
+			return nil, fmt.Errorf("%sの取得に失敗しました: %w", dep.Name, err)
+		}
+		files = append(files, fetched{dep.Name, source, data})
 	}
 
-	// dkjson.luaのダウンロード
-	destPath = filepath.Join(config.AviUtlScriptDir, "dkjson.lua")
-	if err := downloadFileSetup(config.DkjsonLuaURL, destPath); err != nil {
-		return fmt.Errorf("dkjson.luaのダウンロードに失敗しました: %w", err)
+	var sources []string
+	for _, file := range files {
+		destPath := filepath.Join(config.AviUtlScriptDir, file.name)
+		if err := os.WriteFile(destPath, file.data, 0644); err != nil {
+			return nil, fmt.Errorf("%sの書き込みに失敗しました: %w", file.name, err)
+		}
+		sources = append(sources, fmt.Sprintf("  %s: %s", file.name, file.source))
 	}
 
-	fmt.Printf("スクリプトを '%s' へダウンロード・インストールしました。\n", config.AviUtlScriptDir)
-	return nil
+	fmt.Printf("スクリプトを '%s' へインストールしました。\n", config.AviUtlScriptDir)
+	return sources, nil
 }
 
-// downloadFileSetup はファイルをダウンロードする（setup_handler用）
-func downloadFileSetup(url, destPath string) error {
+// downloadSetup はファイルをダウンロードして内容を返す（setup_handler用）
+func downloadSetup(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ダウンロードエラー: %d", resp.StatusCode)
-	}
-
-	file, err := os.Create(destPath)
-	if err != nil {
-		return err
+		return nil, fmt.Errorf("ダウンロードエラー: %d", resp.StatusCode)
 	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
-	return err
+	return io.ReadAll(resp.Body)
 }
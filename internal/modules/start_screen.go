@@ -0,0 +1,354 @@
+package modules
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sekai-overlay-go/internal/utils"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// StartScreenOptions は開始画面の生成オプション
+type StartScreenOptions struct {
+	Format   VideoFormat
+	FontPath string
+	// Theme を指定すると、背景に重ねる色をジャケットの配色に合わせる
+	Theme *Palette
+	// BackgroundZoom は背景画像の拡大率（1080p基準、0の場合はレイアウトの値を使う）
+	BackgroundZoom float64
+}
+
+// startScreenPoint は1080p基準・画面中央を原点とした座標
+type startScreenPoint struct {
+	X, Y float64
+}
+
+// startScreenLayout は開始画面の各要素の配置（テンプレートのレイアウトプリセットと同じ値）
+type startScreenLayout struct {
+	background string
+	bgZoom     float64
+	gradZoom   float64
+	jacket     startScreenPoint
+	jacketBg   startScreenPoint
+	difficulty startScreenPoint
+	title      startScreenPoint
+	credit     startScreenPoint
+}
+
+var startScreenLayouts = map[string]startScreenLayout{
+	LayoutLandscape: {
+		background: "background.png",
+		bgZoom:     120,
+		gradZoom:   150,
+		jacket:     startScreenPoint{-617, 244},
+		jacketBg:   startScreenPoint{-660, 287},
+		difficulty: startScreenPoint{-850, 505},
+		title:      startScreenPoint{-380, 315},
+		credit:     startScreenPoint{-380, 417},
+	},
+	LayoutPortrait: {
+		background: "background_portrait.png",
+		bgZoom:     100,
+		gradZoom:   260,
+		jacket:     startScreenPoint{0, -300},
+		jacketBg:   startScreenPoint{-43, -257},
+		difficulty: startScreenPoint{-233, -39},
+		title:      startScreenPoint{-440, 120},
+		credit:     startScreenPoint{-440, 220},
+	},
+}
+
+// 開始画面のテキスト設定（AviUtlのテキストオブジェクトと同じ値）
+const (
+	startDifficultySize = 31.0
+	startTitleSize      = 40.0
+	startTitleSpacing   = 3.8
+	startCreditSize     = 28.0
+	startCreditLineGap  = -9.0
+)
+
+// defaultStartScreenFont はassets/fontsに同梱している日本語フォント（SIL Open Font License）
+const defaultStartScreenFont = "MPLUS1p-Regular.ttf"
+
+// startScreenTintColor は開始画面の背景に重ねる色
+var startScreenTintColor = color.NRGBA{R: 0x4f, G: 0x4f, B: 0x7d, A: 0xff}
+
+// textAnchor はテキストの縦方向の基準位置
+type textAnchor int
+
+const (
+	anchorMiddle textAnchor = iota
+	anchorBottom
+)
+
+// GenerateStartScreen は開始画面（タイトルカード）の画像を生成する
+// start_screen.pngに完成イメージを、start_text_*.pngにテキストのみの透過画像を出力する
+func GenerateStartScreen(distDir string, info SongInfo, opts StartScreenOptions) error {
+	fmt.Println("開始画面の生成を開始します...")
+
+	format := opts.Format
+	layout, exists := startScreenLayouts[format.Layout]
+	if !exists {
+		layout = startScreenLayouts[LayoutLandscape]
+	}
+
+	fontData, fontName, err := loadStartScreenFont(opts.FontPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  -> フォント '%s' を使用します。\n", fontName)
+
+	// テキストの作成
+	difficultyText := strings.ToUpper(info.Difficulty)
+	if info.Rating > 0 {
+		difficultyText = fmt.Sprintf("%s %s", difficultyText, formatRating(info.Rating))
+	}
+	vocalText := "Inst. ver."
+	if info.Vocal != "" {
+		vocalText = "Vo. " + info.Vocal
+	}
+	creditText := fmt.Sprintf("作詞：%s　作曲：%s　編曲：%s\n%s　譜面制作：%s", info.Words, info.Music, info.Arrange, vocalText, info.Author)
+
+	if missing := missingGlyphs(fontData, difficultyText+info.Title+creditText); missing != "" {
+		fmt.Printf("  -> 警告: フォントに含まれない文字があります: %s\n", missing)
+	}
+
+	// テキストレイヤーの描画
+	texts := []struct {
+		name    string
+		text    string
+		size    float64
+		spacing float64
+		lineGap float64
+		pos     startScreenPoint
+		anchor  textAnchor
+	}{
+		{"difficulty", difficultyText, startDifficultySize, 0, 0, layout.difficulty, anchorBottom},
+		{"title", info.Title, startTitleSize, startTitleSpacing, 0, layout.title, anchorMiddle},
+		{"credit", creditText, startCreditSize, 0, startCreditLineGap, layout.credit, anchorMiddle},
+	}
+
+	textLayers := make([]*image.NRGBA, 0, len(texts))
+	for _, t := range texts {
+		layer := image.NewNRGBA(image.Rect(0, 0, format.Width, format.Height))
+		face, err := opentype.NewFace(fontData, &opentype.FaceOptions{
+			Size:    format.Pos(t.size),
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return fmt.Errorf("フォントの読み込みに失敗しました: %w", err)
+		}
+		x, y := startScreenToPixel(format, t.pos)
+		drawStartScreenText(layer, face, t.text, x, y, format.Pos(t.spacing), format.Pos(t.lineGap), t.anchor)
+		face.Close()
+
+		path := filepath.Join(distDir, fmt.Sprintf("start_text_%s.png", t.name))
+		if err := imaging.Save(layer, path); err != nil {
+			return fmt.Errorf("開始画面テキストの保存に失敗しました: %w", err)
+		}
+		textLayers = append(textLayers, layer)
+	}
+
+	// 完成イメージの合成
+	canvas := imaging.New(format.Width, format.Height, color.Black)
+
+	background, err := imaging.Open(filepath.Join(distDir, layout.background))
+	if err != nil {
+		return fmt.Errorf("背景画像の読み込みに失敗しました: %w", err)
+	}
+	bgZoom := layout.bgZoom
+	if opts.BackgroundZoom > 0 {
+		bgZoom = opts.BackgroundZoom
+	}
+	canvas = placeStartScreenImage(canvas, background, format, startScreenPoint{}, bgZoom, 1.0)
+
+	tintColor := startScreenTintColor
+	if opts.Theme != nil {
+		tintColor = opts.Theme.Shade()
+	}
+	tint := imaging.New(format.Width, format.Height, tintColor)
+	canvas = imaging.Overlay(canvas, tint, image.Point{}, 0.9)
+
+	grad, err := loadAssetImage("assets/startscreen/start_grad.png")
+	if err != nil {
+		return fmt.Errorf("グラデーション画像の読み込みに失敗しました: %w", err)
+	}
+	canvas = placeStartScreenImage(canvas, grad, format, startScreenPoint{}, layout.gradZoom, 1.0)
+
+	// 背景を加算合成で重ねる
+	glow := placeStartScreenImage(imaging.New(format.Width, format.Height, color.Transparent), background, format, startScreenPoint{}, bgZoom, 1.0)
+	addBlend(canvas, glow, 0.15)
+
+	jacketBg, err := loadAssetImage(fmt.Sprintf("assets/startscreen/jacket_bg/%s.png", info.DifficultyImg))
+	if err != nil {
+		return fmt.Errorf("ジャケット背景画像の読み込みに失敗しました: %w", err)
+	}
+	canvas = placeStartScreenImage(canvas, jacketBg, format, layout.jacketBg, 39, 1.0)
+
+	jacket, err := imaging.Open(jacketImagePath(distDir))
+	if err != nil {
+		return fmt.Errorf("ジャケット画像の読み込みに失敗しました: %w", err)
+	}
+	canvas = placeStartScreenImage(canvas, jacket, format, layout.jacket, 78.125, 1.0)
+
+	for _, layer := range textLayers {
+		canvas = imaging.Overlay(canvas, layer, image.Point{}, 1.0)
+	}
+
+	outputPath := filepath.Join(distDir, "start_screen.png")
+	if err := imaging.Save(canvas, outputPath); err != nil {
+		return fmt.Errorf("開始画面の保存に失敗しました: %w", err)
+	}
+
+	fmt.Printf("開始画面を '%s' に保存しました。\n", outputPath)
+	return nil
+}
+
+// loadStartScreenFont はフォントを読み込む
+// 指定がない場合は同梱の日本語フォント（M PLUS 1p）、それもなければassets/fonts内のフォントを使用する
+// どちらもない場合は日本語が表示できないため、エラーを返す
+func loadStartScreenFont(fontPath string) (*sfnt.Font, string, error) {
+	if fontPath == "" {
+		fontsDir := utils.ResourcePath("assets/fonts")
+		if _, err := os.Stat(filepath.Join(fontsDir, defaultStartScreenFont)); err == nil {
+			fontPath = filepath.Join(fontsDir, defaultStartScreenFont)
+		}
+		for _, pattern := range []string{"*.otf", "*.ttf", "*.ttc", "*.otc"} {
+			if fontPath != "" {
+				break
+			}
+			if matches, _ := filepath.Glob(filepath.Join(fontsDir, pattern)); len(matches) > 0 {
+				fontPath = matches[0]
+			}
+		}
+	}
+
+	if fontPath == "" {
+		return nil, "", fmt.Errorf("開始画面のフォントが見つかりません。日本語フォントのパスを指定するか、assets/fontsに%sを配置してください", defaultStartScreenFont)
+	}
+
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("フォントファイルの読み込みに失敗しました: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fontPath))
+	if ext == ".ttc" || ext == ".otc" {
+		collection, err := opentype.ParseCollection(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("フォントコレクションの解析に失敗しました: %w", err)
+		}
+		f, err := collection.Font(0)
+		if err != nil {
+			return nil, "", fmt.Errorf("フォントコレクションの解析に失敗しました: %w", err)
+		}
+		return f, filepath.Base(fontPath), nil
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("フォントの解析に失敗しました: %w", err)
+	}
+	return f, filepath.Base(fontPath), nil
+}
+
+// missingGlyphs はフォントに含まれない文字を返す
+func missingGlyphs(f *sfnt.Font, text string) string {
+	var buf sfnt.Buffer
+	seen := map[rune]bool{}
+	var missing []rune
+	for _, r := range text {
+		if r == '\n' || r == ' ' || seen[r] {
+			continue
+		}
+		seen[r] = true
+		if index, err := f.GlyphIndex(&buf, r); err != nil || index == 0 {
+			missing = append(missing, r)
+		}
+	}
+	return string(missing)
+}
+
+// formatRating はレーティングを表示用の文字列に変換する
+func formatRating(rating float64) string {
+	if rating == math.Trunc(rating) {
+		return fmt.Sprintf("%d", int(rating))
+	}
+	return fmt.Sprintf("%g", rating)
+}
+
+// startScreenToPixel は画面中央を原点とした1080p基準の座標を出力画像のピクセル座標に変換する
+func startScreenToPixel(format VideoFormat, p startScreenPoint) (float64, float64) {
+	return float64(format.Width)/2 + format.Pos(p.X), float64(format.Height)/2 + format.Pos(p.Y)
+}
+
+// placeStartScreenImage は画像を指定した中心座標・拡大率で重ねる
+func placeStartScreenImage(dst *image.NRGBA, src image.Image, format VideoFormat, center startScreenPoint, zoom, opacity float64) *image.NRGBA {
+	scale := format.Pos(zoom) / 100
+	w := int(math.Round(float64(src.Bounds().Dx()) * scale))
+	h := int(math.Round(float64(src.Bounds().Dy()) * scale))
+	if w <= 0 || h <= 0 {
+		return dst
+	}
+	resized := imaging.Resize(src, w, h, imaging.Lanczos)
+
+	cx, cy := startScreenToPixel(format, center)
+	pos := image.Pt(int(math.Round(cx-float64(w)/2)), int(math.Round(cy-float64(h)/2)))
+	return imaging.Overlay(dst, resized, pos, opacity)
+}
+
+// addBlend は画像を加算合成する
+func addBlend(dst, src *image.NRGBA, opacity float64) {
+	for i := 0; i+3 < len(dst.Pix) && i+3 < len(src.Pix); i += 4 {
+		a := float64(src.Pix[i+3]) / 255 * opacity
+		for c := 0; c < 3; c++ {
+			v := float64(dst.Pix[i+c]) + float64(src.Pix[i+c])*a
+			dst.Pix[i+c] = uint8(math.Min(255, v))
+		}
+	}
+}
+
+// drawStartScreenText はテキストを左寄せで描画する
+// anchorMiddleはyを全体の縦中央、anchorBottomはyを最終行の下端として扱う
+func drawStartScreenText(dst *image.NRGBA, face font.Face, text string, x, y, spacing, lineGap float64, anchor textAnchor) {
+	metrics := face.Metrics()
+	ascent := float64(metrics.Ascent) / 64
+	descent := float64(metrics.Descent) / 64
+	lineHeight := ascent + descent + lineGap
+
+	lines := strings.Split(text, "\n")
+	totalHeight := lineHeight*float64(len(lines)) - lineGap
+
+	top := y - totalHeight/2
+	if anchor == anchorBottom {
+		top = y - totalHeight
+	}
+
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+	}
+	for i, line := range lines {
+		baseline := top + ascent + lineHeight*float64(i)
+		drawer.Dot = fixed.Point26_6{
+			X: fixed.Int26_6(x * 64),
+			Y: fixed.Int26_6(baseline * 64),
+		}
+		for _, r := range line {
+			drawer.DrawString(string(r))
+			drawer.Dot.X += fixed.Int26_6(spacing * 64)
+		}
+	}
+}
@@ -0,0 +1,849 @@
+package modules
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 汎用タイムラインの出力ファイル名
+const (
+	mltFileName    = "timeline.mlt"
+	fcpxmlFileName = "timeline.fcpxml"
+)
+
+// endScreenVideoWidth, endScreenVideoHeight は同梱のエンド画面動画の解像度
+const (
+	endScreenVideoWidth  = 1920
+	endScreenVideoHeight = 1080
+)
+
+// editorClipKind は汎用タイムラインのクリップの種類
+type editorClipKind int
+
+const (
+	clipImage editorClipKind = iota
+	clipSequence
+	clipVideo
+	clipAudio
+	clipColor
+)
+
+// opacityKey は不透明度のキーフレーム（タイムラインのフレーム番号）
+type opacityKey struct {
+	Frame int
+	Value float64
+}
+
+// editorClip は編集ソフトに依存しないクリップの情報
+type editorClip struct {
+	Name  string
+	Kind  editorClipKind
+	Layer int
+	Path  string
+	Color string
+	// Start, End はタイムライン上の開始・終了フレーム（終了フレームを含む）
+	Start, End int
+	// SourceIn は素材の再生開始位置（秒）
+	SourceIn float64
+	// X, Y, Width, Height は出力解像度での表示位置とサイズ
+	X, Y, Width, Height float64
+	// SourceWidth, SourceHeight は素材の解像度
+	SourceWidth, SourceHeight int
+	Opacity                   []opacityKey
+	Volume                    float64
+	// Screen はスクリーン合成で重ねるか（黒を透過するunmultの代わり）
+	Screen bool
+}
+
+// Length はクリップのフレーム数を返す
+func (c editorClip) Length() int {
+	return c.End - c.Start + 1
+}
+
+// opacityAt は指定フレームの不透明度を返す
+func (c editorClip) opacityAt(frame int) float64 {
+	if len(c.Opacity) == 0 {
+		return 1
+	}
+	if frame <= c.Opacity[0].Frame {
+		return c.Opacity[0].Value
+	}
+	for i := 1; i < len(c.Opacity); i++ {
+		prev, next := c.Opacity[i-1], c.Opacity[i]
+		if frame <= next.Frame {
+			if next.Frame == prev.Frame {
+				return next.Value
+			}
+			t := float64(frame-prev.Frame) / float64(next.Frame-prev.Frame)
+			return prev.Value + (next.Value-prev.Value)*t
+		}
+	}
+	return c.Opacity[len(c.Opacity)-1].Value
+}
+
+// editorTimeline は編集ソフトに依存しないタイムライン
+type editorTimeline struct {
+	Name   string
+	Format VideoFormat
+	Length int
+	Clips  []editorClip
+}
+
+// timelineSequences はタイムラインに配置する連番画像の種類
+type timelineSequences struct {
+	// overlay はHUDの連番画像(overlay/)
+	overlay bool
+	// preview は譜面プレビューの連番画像(preview/)
+	preview bool
+}
+
+// objectPathMap はテンプレートで使用したパスを実際のファイルパスに戻すための対応表
+type objectPathMap struct {
+	distPath, distDir     string
+	assetsPath, assetsDir string
+}
+
+// resolve は.object内のファイルパスを実際のファイルパスに変換する
+func (m objectPathMap) resolve(value string) string {
+	for _, pair := range [][2]string{{m.distPath, m.distDir}, {m.assetsPath, m.assetsDir}} {
+		if strings.HasPrefix(value, pair[0]+"\\") {
+			rel := strings.ReplaceAll(strings.TrimPrefix(value, pair[0]+"\\"), "\\", "/")
+			return filepath.Join(pair[1], filepath.FromSlash(rel))
+		}
+	}
+	return value
+}
+
+// writeTimelineFiles はエイリアスオブジェクトの内容からMLT XMLとFCPXMLのタイムラインを書き出す
+// 背景・BGM・エンド画面の動画・暗転と、出力した連番画像（MLTのみ）を配置し、タイミングはmain.objectと同じにする
+func writeTimelineFiles(distDir, name string, objectFile *ObjectFile, format VideoFormat, paths objectPathMap, sequences timelineSequences) error {
+	timeline, err := buildEditorTimeline(distDir, name, objectFile, format, paths, sequences)
+	if err != nil {
+		return err
+	}
+
+	outputs := []struct {
+		fileName string
+		write    func(editorTimeline) ([]byte, error)
+	}{
+		{mltFileName, buildMLT},
+		{fcpxmlFileName, buildFCPXML},
+	}
+	for _, output := range outputs {
+		data, err := output.write(timeline)
+		if err != nil {
+			return err
+		}
+		outputPath := filepath.Join(distDir, output.fileName)
+		if err := os.WriteFile(outputPath, data, 0644); err != nil {
+			return fmt.Errorf("タイムラインファイルの書き込みに失敗しました: %w", err)
+		}
+		fmt.Printf("タイムラインを '%s' に保存しました。\n", outputPath)
+	}
+	return nil
+}
+
+// buildEditorTimeline はmain.objectから汎用タイムラインに必要なオブジェクトを取り出す
+func buildEditorTimeline(distDir, name string, objectFile *ObjectFile, format VideoFormat, paths objectPathMap, sequences timelineSequences) (editorTimeline, error) {
+	timeline := editorTimeline{
+		Name:   name,
+		Format: format,
+		Length: objectFile.EndFrame() + 1,
+	}
+
+	for _, obj := range objectFile.Objects {
+		if clip, ok := editorClipFromObject(obj, format, paths); ok {
+			timeline.Clips = append(timeline.Clips, clip)
+		}
+	}
+
+	// 譜面プレビューの連番画像はレーンを含むので、レーンのレイヤーに置く
+	if sequences.preview {
+		for _, obj := range objectFile.Objects {
+			if effect := obj.Effect("画像ファイル"); effect != nil {
+				if file, _ := effect.Get("ファイル"); filepath.Base(paths.resolve(file)) == "lane.png" {
+					timeline.Clips = append(timeline.Clips, fullFrameSequence("preview", obj.Layer, filepath.Join(distDir, chartPreviewDirName), timeline.Length, format))
+					break
+				}
+			}
+		}
+	}
+
+	// HUDの連番画像はSekaiObjectsの一番上のレイヤーに置く
+	if sequences.overlay {
+		layer := 0
+		for _, obj := range objectFile.Objects {
+			for _, effect := range obj.Effects {
+				if strings.HasSuffix(effect.Name, "@SekaiObjects") && obj.Layer > layer {
+					layer = obj.Layer
+				}
+			}
+		}
+		timeline.Clips = append(timeline.Clips, fullFrameSequence("overlay", layer, filepath.Join(distDir, overlayDirName), timeline.Length, format))
+	}
+
+	if len(timeline.Clips) == 0 {
+		return editorTimeline{}, fmt.Errorf("タイムラインに配置できるオブジェクトがありません")
+	}
+	sort.SliceStable(timeline.Clips, func(i, j int) bool { return timeline.Clips[i].Layer < timeline.Clips[j].Layer })
+	return timeline, nil
+}
+
+// fullFrameSequence はタイムラインの先頭から並ぶ、画面全体の連番画像のクリップを作成する
+func fullFrameSequence(name string, layer int, dir string, length int, format VideoFormat) editorClip {
+	return editorClip{
+		Name:         name,
+		Kind:         clipSequence,
+		Layer:        layer,
+		Path:         filepath.Join(dir, "%06d.png"),
+		Start:        0,
+		End:          length - 1,
+		Width:        float64(format.Width),
+		Height:       float64(format.Height),
+		SourceWidth:  format.Width,
+		SourceHeight: format.Height,
+	}
+}
+
+// editorClipFromObject はオブジェクトを汎用タイムラインのクリップに変換する
+// 背景画像・BGM・エンド画面の動画・塗りつぶし（暗転）のみを対象とする
+func editorClipFromObject(obj *TimelineObject, format VideoFormat, paths objectPathMap) (editorClip, bool) {
+	clip := editorClip{Layer: obj.Layer, Start: obj.Start(), End: obj.End(), Volume: 1}
+
+	switch {
+	case obj.Effect("画像ファイル") != nil:
+		file, _ := obj.Effect("画像ファイル").Get("ファイル")
+		if !strings.HasPrefix(filepath.Base(paths.resolve(file)), "background") {
+			return editorClip{}, false
+		}
+		clip.Name = "background"
+		clip.Kind = clipImage
+		clip.Path = paths.resolve(file)
+		config, err := readImageSize(clip.Path)
+		if err != nil {
+			return editorClip{}, false
+		}
+		clip.SourceWidth, clip.SourceHeight = config.X, config.Y
+		clip.placeObject(obj.Effect("標準描画"), format)
+
+	case obj.Effect("動画ファイル") != nil:
+		video := obj.Effect("動画ファイル")
+		file, _ := video.Get("ファイル")
+		clip.Name = "endscreen"
+		clip.Kind = clipVideo
+		clip.Path = paths.resolve(file)
+		clip.SourceIn = effectFloat(video, "再生位置", 0)
+		clip.SourceWidth, clip.SourceHeight = endScreenVideoWidth, endScreenVideoHeight
+		playback := obj.Effect("映像再生")
+		clip.placeObject(playback, format)
+		clip.Volume = effectFloat(playback, "音量", 100) / 100
+		clip.Screen = obj.Effect("unmult") != nil
+
+	case obj.Effect("音声ファイル") != nil:
+		audio := obj.Effect("音声ファイル")
+		file, _ := audio.Get("ファイル")
+		clip.Name = "music"
+		clip.Kind = clipAudio
+		clip.Path = paths.resolve(file)
+		clip.SourceIn = effectFloat(audio, "再生位置", 0)
+		clip.Volume = effectFloat(obj.Effect("音声再生"), "音量", 100) / 100
+
+	case obj.Effect("図形") != nil:
+		shape := obj.Effect("図形")
+		if kind, _ := shape.Get("図形の種類"); kind != "背景" {
+			return editorClip{}, false
+		}
+		clip.Name = "solid"
+		clip.Kind = clipColor
+		clip.Color, _ = shape.Get("色")
+		clip.Width, clip.Height = float64(format.Width), float64(format.Height)
+		clip.Opacity = opacityKeys(obj)
+
+	default:
+		return editorClip{}, false
+	}
+	return clip, true
+}
+
+// placeObject は標準描画・映像再生の座標と拡大率から表示位置とサイズを求める
+func (c *editorClip) placeObject(draw *ObjectEffect, format VideoFormat) {
+	zoom := effectFloat(draw, "拡大率", 100) / 100
+	c.Width = float64(c.SourceWidth) * zoom
+	c.Height = float64(c.SourceHeight) * zoom
+	c.X = float64(format.Width)/2 + effectFloat(draw, "X", 0) - c.Width/2
+	c.Y = float64(format.Height)/2 + effectFloat(draw, "Y", 0) - c.Height/2
+}
+
+// opacityKeys は標準描画の透明度を不透明度のキーフレームに変換する
+// 直線移動の場合は中間点を含む各フレームに値が対応する
+func opacityKeys(obj *TimelineObject) []opacityKey {
+	draw := obj.Effect("標準描画")
+	if draw == nil {
+		return nil
+	}
+	value, err := draw.Animated("透明度")
+	if err != nil {
+		return nil
+	}
+	if !value.IsAnimated() {
+		return []opacityKey{{Frame: obj.Start(), Value: 1 - value.Values[0]/100}}
+	}
+
+	keys := make([]opacityKey, 0, len(obj.Frames))
+	for i, frame := range obj.Frames {
+		v := value.Values[len(value.Values)-1]
+		if i < len(value.Values) {
+			v = value.Values[i]
+		}
+		keys = append(keys, opacityKey{Frame: frame, Value: 1 - v/100})
+	}
+	return keys
+}
+
+// readImageSize は画像の解像度を取得する
+func readImageSize(path string) (image.Point, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Point{}, err
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Point{}, err
+	}
+	return image.Pt(config.Width, config.Height), nil
+}
+
+// --- MLT XML (Kdenlive / Shotcut) ---
+
+type mltDocument struct {
+	XMLName   xml.Name      `xml:"mlt"`
+	LCNumeric string        `xml:"LC_NUMERIC,attr"`
+	Version   string        `xml:"version,attr"`
+	Title     string        `xml:"title,attr"`
+	Producer  string        `xml:"producer,attr"`
+	Profile   mltProfile    `xml:"profile"`
+	Producers []mltProducer `xml:"producer"`
+	Playlists []mltPlaylist `xml:"playlist"`
+	Tractor   mltTractor    `xml:"tractor"`
+}
+
+type mltProfile struct {
+	Description      string `xml:"description,attr"`
+	Width            int    `xml:"width,attr"`
+	Height           int    `xml:"height,attr"`
+	Progressive      int    `xml:"progressive,attr"`
+	SampleAspectNum  int    `xml:"sample_aspect_num,attr"`
+	SampleAspectDen  int    `xml:"sample_aspect_den,attr"`
+	DisplayAspectNum int    `xml:"display_aspect_num,attr"`
+	DisplayAspectDen int    `xml:"display_aspect_den,attr"`
+	FrameRateNum     int    `xml:"frame_rate_num,attr"`
+	FrameRateDen     int    `xml:"frame_rate_den,attr"`
+	Colorspace       int    `xml:"colorspace,attr"`
+}
+
+type mltProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type mltFilter struct {
+	Properties []mltProperty `xml:"property"`
+}
+
+type mltProducer struct {
+	ID         string        `xml:"id,attr"`
+	In         int           `xml:"in,attr"`
+	Out        int           `xml:"out,attr"`
+	Properties []mltProperty `xml:"property"`
+	Filters    []mltFilter   `xml:"filter"`
+}
+
+type mltPlaylistItem struct {
+	XMLName  xml.Name
+	Producer string `xml:"producer,attr,omitempty"`
+	In       *int   `xml:"in,attr"`
+	Out      *int   `xml:"out,attr"`
+	Length   *int   `xml:"length,attr"`
+}
+
+type mltPlaylist struct {
+	ID    string            `xml:"id,attr"`
+	Items []mltPlaylistItem `xml:",any"`
+}
+
+type mltTrack struct {
+	Producer string `xml:"producer,attr"`
+	Hide     string `xml:"hide,attr,omitempty"`
+}
+
+type mltTransition struct {
+	Properties []mltProperty `xml:"property"`
+}
+
+type mltTractor struct {
+	ID          string          `xml:"id,attr"`
+	In          int             `xml:"in,attr"`
+	Out         int             `xml:"out,attr"`
+	Tracks      []mltTrack      `xml:"track"`
+	Transitions []mltTransition `xml:"transition"`
+}
+
+// buildMLT は汎用タイムラインをMLT XMLに変換する
+// クリップごとにトラックを作り、映像はqtblend、音声はmixで下のトラックと合成する
+func buildMLT(timeline editorTimeline) ([]byte, error) {
+	format := timeline.Format
+	gcd := greatestCommonDivisor(format.Width, format.Height)
+	doc := mltDocument{
+		LCNumeric: "C",
+		Version:   "7.0.0",
+		Title:     timeline.Name,
+		Producer:  "main_tractor",
+		Profile: mltProfile{
+			Description:      format.String(),
+			Width:            format.Width,
+			Height:           format.Height,
+			Progressive:      1,
+			SampleAspectNum:  1,
+			SampleAspectDen:  1,
+			DisplayAspectNum: format.Width / gcd,
+			DisplayAspectDen: format.Height / gcd,
+			FrameRateNum:     format.FPS,
+			FrameRateDen:     1,
+			Colorspace:       709,
+		},
+		Tractor: mltTractor{ID: "main_tractor", In: 0, Out: timeline.Length - 1},
+	}
+
+	for i, clip := range timeline.Clips {
+		producerID := fmt.Sprintf("producer%d", i)
+		playlistID := fmt.Sprintf("playlist%d", i)
+		sourceIn := int(math.Round(clip.SourceIn * float64(format.FPS)))
+		in, out := sourceIn, sourceIn+clip.Length()-1
+
+		producer := mltProducer{ID: producerID, In: in, Out: out}
+		props := []mltProperty{{"length", fmt.Sprint(out + 1)}}
+		switch clip.Kind {
+		case clipImage:
+			props = append(props, mltProperty{"resource", clip.Path}, mltProperty{"mlt_service", "qimage"})
+		case clipSequence:
+			props = append(props,
+				mltProperty{"resource", clip.Path},
+				mltProperty{"mlt_service", "qimage"},
+				mltProperty{"begin", "0"},
+				mltProperty{"ttl", "1"},
+			)
+		case clipVideo, clipAudio:
+			props = append(props, mltProperty{"resource", clip.Path}, mltProperty{"mlt_service", "avformat"})
+			if clip.Kind == clipAudio {
+				props = append(props, mltProperty{"video_index", "-1"})
+			}
+		case clipColor:
+			props = append(props, mltProperty{"resource", "#ff" + clip.Color}, mltProperty{"mlt_service", "color"})
+		}
+		props = append(props, mltProperty{"kdenlive:clipname", clip.Name}, mltProperty{"shotcut:caption", clip.Name})
+		producer.Properties = props
+
+		if clip.Kind != clipAudio {
+			producer.Filters = append(producer.Filters, mltFilter{Properties: []mltProperty{
+				{"mlt_service", "affine"},
+				{"transition.rect", mltRect(clip)},
+				{"transition.fill", "1"},
+				{"transition.distort", "0"},
+			}})
+		}
+		if clip.Volume != 1 && (clip.Kind == clipVideo || clip.Kind == clipAudio) {
+			producer.Filters = append(producer.Filters, mltFilter{Properties: []mltProperty{
+				{"mlt_service", "volume"},
+				{"gain", fmt.Sprintf("%.3f", clip.Volume)},
+			}})
+		}
+		doc.Producers = append(doc.Producers, producer)
+
+		playlist := mltPlaylist{ID: playlistID}
+		if clip.Start > 0 {
+			length := clip.Start
+			playlist.Items = append(playlist.Items, mltPlaylistItem{XMLName: xml.Name{Local: "blank"}, Length: &length})
+		}
+		entryIn, entryOut := in, out
+		playlist.Items = append(playlist.Items, mltPlaylistItem{XMLName: xml.Name{Local: "entry"}, Producer: producerID, In: &entryIn, Out: &entryOut})
+		doc.Playlists = append(doc.Playlists, playlist)
+
+		track := mltTrack{Producer: playlistID}
+		switch clip.Kind {
+		case clipAudio:
+			track.Hide = "video"
+		case clipImage, clipSequence, clipColor:
+			track.Hide = "audio"
+		}
+		doc.Tractor.Tracks = append(doc.Tractor.Tracks, track)
+
+		// 2本目以降のトラックは下のトラックと合成する
+		if i == 0 {
+			continue
+		}
+		if clip.Kind != clipAudio {
+			compositing := "0"
+			if clip.Screen {
+				// QPainter::CompositionMode_Screen
+				compositing = "15"
+			}
+			doc.Tractor.Transitions = append(doc.Tractor.Transitions, mltTransition{Properties: []mltProperty{
+				{"a_track", "0"},
+				{"b_track", fmt.Sprint(i)},
+				{"mlt_service", "qtblend"},
+				{"compositing", compositing},
+				{"always_active", "1"},
+			}})
+		}
+		if clip.Kind == clipVideo || clip.Kind == clipAudio {
+			doc.Tractor.Transitions = append(doc.Tractor.Transitions, mltTransition{Properties: []mltProperty{
+				{"a_track", "0"},
+				{"b_track", fmt.Sprint(i)},
+				{"mlt_service", "mix"},
+				{"sum", "1"},
+				{"always_active", "1"},
+			}})
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("MLT XMLの作成に失敗しました: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// mltRect はaffineフィルタの表示位置（不透明度のキーフレームを含む）を作成する
+// キーフレームのフレーム番号はクリップの先頭からの相対位置
+func mltRect(clip editorClip) string {
+	rect := fmt.Sprintf("%.2f %.2f %.2f %.2f", clip.X, clip.Y, clip.Width, clip.Height)
+	if len(clip.Opacity) <= 1 {
+		return fmt.Sprintf("%s %.3f", rect, clip.opacityAt(clip.Start))
+	}
+	keys := make([]string, 0, len(clip.Opacity))
+	for _, key := range clip.Opacity {
+		keys = append(keys, fmt.Sprintf("%d=%s %.3f", key.Frame-clip.Start, rect, key.Value))
+	}
+	return strings.Join(keys, ";")
+}
+
+// greatestCommonDivisor は最大公約数を返す
+func greatestCommonDivisor(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// --- FCPXML (DaVinci Resolve / Final Cut Pro) ---
+
+type fcpxmlDocument struct {
+	XMLName   xml.Name        `xml:"fcpxml"`
+	Version   string          `xml:"version,attr"`
+	Resources fcpxmlResources `xml:"resources"`
+	Library   fcpxmlLibrary   `xml:"library"`
+}
+
+type fcpxmlResources struct {
+	Formats []fcpxmlFormat `xml:"format"`
+	Assets  []fcpxmlAsset  `xml:"asset"`
+}
+
+type fcpxmlFormat struct {
+	ID            string `xml:"id,attr"`
+	Name          string `xml:"name,attr,omitempty"`
+	FrameDuration string `xml:"frameDuration,attr,omitempty"`
+	Width         int    `xml:"width,attr"`
+	Height        int    `xml:"height,attr"`
+}
+
+type fcpxmlAsset struct {
+	ID            string `xml:"id,attr"`
+	Name          string `xml:"name,attr"`
+	Src           string `xml:"src,attr"`
+	Start         string `xml:"start,attr"`
+	Duration      string `xml:"duration,attr"`
+	HasVideo      string `xml:"hasVideo,attr,omitempty"`
+	HasAudio      string `xml:"hasAudio,attr,omitempty"`
+	Format        string `xml:"format,attr,omitempty"`
+	AudioSources  string `xml:"audioSources,attr,omitempty"`
+	AudioChannels string `xml:"audioChannels,attr,omitempty"`
+}
+
+type fcpxmlLibrary struct {
+	Event fcpxmlEvent `xml:"event"`
+}
+
+type fcpxmlEvent struct {
+	Name    string        `xml:"name,attr"`
+	Project fcpxmlProject `xml:"project"`
+}
+
+type fcpxmlProject struct {
+	Name     string         `xml:"name,attr"`
+	Sequence fcpxmlSequence `xml:"sequence"`
+}
+
+type fcpxmlSequence struct {
+	Format   string      `xml:"format,attr"`
+	Duration string      `xml:"duration,attr"`
+	TCStart  string      `xml:"tcStart,attr"`
+	TCFormat string      `xml:"tcFormat,attr"`
+	Spine    fcpxmlSpine `xml:"spine"`
+}
+
+type fcpxmlSpine struct {
+	Gap fcpxmlGap `xml:"gap"`
+}
+
+type fcpxmlGap struct {
+	Name     string            `xml:"name,attr"`
+	Offset   string            `xml:"offset,attr"`
+	Duration string            `xml:"duration,attr"`
+	Start    string            `xml:"start,attr"`
+	Clips    []fcpxmlAssetClip `xml:"asset-clip"`
+}
+
+type fcpxmlAssetClip struct {
+	Ref       string           `xml:"ref,attr"`
+	Lane      int              `xml:"lane,attr"`
+	Offset    string           `xml:"offset,attr"`
+	Name      string           `xml:"name,attr"`
+	Start     string           `xml:"start,attr"`
+	Duration  string           `xml:"duration,attr"`
+	Transform *fcpxmlTransform `xml:"adjust-transform,omitempty"`
+	Blend     *fcpxmlBlend     `xml:"adjust-blend,omitempty"`
+	Volume    *fcpxmlVolume    `xml:"adjust-volume,omitempty"`
+}
+
+type fcpxmlTransform struct {
+	Position string `xml:"position,attr"`
+	Scale    string `xml:"scale,attr"`
+}
+
+type fcpxmlBlend struct {
+	Amount string        `xml:"amount,attr"`
+	Params []fcpxmlParam `xml:"param,omitempty"`
+}
+
+type fcpxmlParam struct {
+	Name      string                  `xml:"name,attr"`
+	Keyframes fcpxmlKeyframeAnimation `xml:"keyframeAnimation"`
+}
+
+type fcpxmlKeyframeAnimation struct {
+	Keyframes []fcpxmlKeyframe `xml:"keyframe"`
+}
+
+type fcpxmlKeyframe struct {
+	Time  string `xml:"time,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type fcpxmlVolume struct {
+	Amount string `xml:"amount,attr"`
+}
+
+// buildFCPXML は汎用タイムラインをFCPXMLに変換する
+// 塗りつぶし（暗転）はクリップとして置かず、下にある映像クリップの不透明度のキーフレームで表現する
+// FCPXMLのassetは連番画像を参照できない（先頭の1枚の静止画になる）ため、連番画像のクリップは含めない
+func buildFCPXML(timeline editorTimeline) ([]byte, error) {
+	format := timeline.Format
+	rational := func(frames int) string {
+		if frames == 0 {
+			return "0s"
+		}
+		return fmt.Sprintf("%d/%ds", frames, format.FPS)
+	}
+
+	doc := fcpxmlDocument{
+		Version: "1.9",
+		Resources: fcpxmlResources{
+			Formats: []fcpxmlFormat{{
+				ID:            "r1",
+				Name:          fmt.Sprintf("FFVideoFormat%dx%dp%d", format.Width, format.Height, format.FPS),
+				FrameDuration: fmt.Sprintf("1/%ds", format.FPS),
+				Width:         format.Width,
+				Height:        format.Height,
+			}},
+		},
+		Library: fcpxmlLibrary{Event: fcpxmlEvent{
+			Name: timeline.Name,
+			Project: fcpxmlProject{
+				Name: timeline.Name,
+				Sequence: fcpxmlSequence{
+					Format:   "r1",
+					Duration: rational(timeline.Length),
+					TCStart:  "0s",
+					TCFormat: "NDF",
+					Spine: fcpxmlSpine{Gap: fcpxmlGap{
+						Name:     "Gap",
+						Offset:   "0s",
+						Duration: rational(timeline.Length),
+						Start:    "0s",
+					}},
+				},
+			},
+		}},
+	}
+
+	var solids []editorClip
+	for _, clip := range timeline.Clips {
+		if clip.Kind == clipColor {
+			solids = append(solids, clip)
+		}
+	}
+
+	lane := 1
+	for i, clip := range timeline.Clips {
+		if clip.Kind == clipColor || clip.Kind == clipSequence {
+			continue
+		}
+		assetID := fmt.Sprintf("r%d", i+2)
+		sourceIn := int(math.Round(clip.SourceIn * float64(format.FPS)))
+
+		asset := fcpxmlAsset{
+			ID:       assetID,
+			Name:     clip.Name,
+			Src:      fileURL(clip.Path),
+			Start:    "0s",
+			Duration: rational(sourceIn + clip.Length()),
+		}
+		switch clip.Kind {
+		case clipImage:
+			asset.HasVideo = "1"
+			asset.Format = "r1"
+			asset.Duration = "0s"
+		case clipVideo:
+			asset.HasVideo = "1"
+			asset.HasAudio = "1"
+			asset.Format = "r1"
+			asset.AudioSources = "1"
+			asset.AudioChannels = "2"
+		case clipAudio:
+			asset.HasAudio = "1"
+			asset.AudioSources = "1"
+			asset.AudioChannels = "2"
+		}
+		doc.Resources.Assets = append(doc.Resources.Assets, asset)
+
+		assetClip := fcpxmlAssetClip{
+			Ref:      assetID,
+			Offset:   rational(clip.Start),
+			Name:     clip.Name,
+			Start:    rational(sourceIn),
+			Duration: rational(clip.Length()),
+		}
+		if clip.Kind == clipAudio {
+			assetClip.Lane = -1
+		} else {
+			assetClip.Lane = lane
+			lane++
+			assetClip.Transform = fcpxmlPlacement(clip, format)
+			assetClip.Blend = fcpxmlOpacity(clip, solids, sourceIn, rational)
+		}
+		if clip.Volume != 1 && (clip.Kind == clipVideo || clip.Kind == clipAudio) {
+			assetClip.Volume = &fcpxmlVolume{Amount: fmt.Sprintf("%.1fdB", 20*math.Log10(math.Max(clip.Volume, 0.001)))}
+		}
+		doc.Library.Event.Project.Sequence.Spine.Gap.Clips = append(doc.Library.Event.Project.Sequence.Spine.Gap.Clips, assetClip)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("FCPXMLの作成に失敗しました: %w", err)
+	}
+	return append([]byte(xml.Header+"<!DOCTYPE fcpxml>\n"), append(data, '\n')...), nil
+}
+
+// fcpxmlPlacement はFCPXMLの位置と拡大率を求める
+// FCPXMLは素材を画面に収まるように配置した状態を基準とし、位置は画面の高さを100とした単位で表す
+func fcpxmlPlacement(clip editorClip, format VideoFormat) *fcpxmlTransform {
+	if clip.SourceWidth == 0 || clip.SourceHeight == 0 {
+		return nil
+	}
+	fit := math.Min(float64(format.Width)/float64(clip.SourceWidth), float64(format.Height)/float64(clip.SourceHeight))
+	scale := clip.Width / (float64(clip.SourceWidth) * fit)
+	unit := 100 / float64(format.Height)
+	x := (clip.X + clip.Width/2 - float64(format.Width)/2) * unit
+	y := -(clip.Y + clip.Height/2 - float64(format.Height)/2) * unit
+	return &fcpxmlTransform{
+		Position: fmt.Sprintf("%.3f %.3f", x, y),
+		Scale:    fmt.Sprintf("%.4f %.4f", scale, scale),
+	}
+}
+
+// fcpxmlOpacity は上に重なる塗りつぶしの不透明度から、クリップの不透明度のキーフレームを作成する
+// キーフレームの時刻は素材の時間（start基準）で表す
+func fcpxmlOpacity(clip editorClip, solids []editorClip, sourceIn int, rational func(int) string) *fcpxmlBlend {
+	frameSet := map[int]bool{}
+	for _, solid := range solids {
+		if solid.Layer <= clip.Layer || solid.End < clip.Start || solid.Start > clip.End {
+			continue
+		}
+		frameSet[max(solid.Start, clip.Start)] = true
+		if solid.Start > clip.Start {
+			frameSet[solid.Start-1] = true
+		}
+		for _, key := range solid.Opacity {
+			if key.Frame >= clip.Start && key.Frame <= clip.End {
+				frameSet[key.Frame] = true
+			}
+		}
+	}
+	if len(frameSet) == 0 {
+		return nil
+	}
+
+	amountAt := func(frame int) float64 {
+		amount := 1.0
+		for _, solid := range solids {
+			if solid.Layer > clip.Layer && frame >= solid.Start && frame <= solid.End {
+				amount *= 1 - solid.opacityAt(frame)
+			}
+		}
+		return amount
+	}
+
+	frames := make([]int, 0, len(frameSet))
+	for frame := range frameSet {
+		frames = append(frames, frame)
+	}
+	sort.Ints(frames)
+
+	blend := &fcpxmlBlend{Amount: fmt.Sprintf("%.3f", amountAt(clip.Start))}
+	if len(frames) == 1 {
+		return blend
+	}
+	param := fcpxmlParam{Name: "amount"}
+	for _, frame := range frames {
+		param.Keyframes.Keyframes = append(param.Keyframes.Keyframes, fcpxmlKeyframe{
+			Time:  rational(sourceIn + frame - clip.Start),
+			Value: fmt.Sprintf("%.3f", amountAt(frame)),
+		})
+	}
+	blend.Params = []fcpxmlParam{param}
+	return blend
+}
+
+// fileURL はファイルパスをfile:// URLに変換する
+func fileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	slashed := filepath.ToSlash(abs)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
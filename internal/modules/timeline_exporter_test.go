@@ -0,0 +1,256 @@
+package modules
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// testTimelineObject は背景・BGM・エンド画面・暗転・レーン・HUDを並べた小さな.object
+// （レイヤー番号の昇順とセクションの順序をわざと変えている）
+const testTimelineObject = `[0]
+layer=3
+frame=600,899
+[0.0]
+effect.name=動画ファイル
+ファイル=C:\assets\endscreen\v3\ap.mp4
+再生位置=0.00
+[0.1]
+effect.name=映像再生
+X=0.00
+Y=0.00
+拡大率=100.000
+透明度=0.00
+音量=50.0
+[1]
+layer=1
+frame=0,899
+[1.0]
+effect.name=画像ファイル
+ファイル=C:\dist\test\background.png
+[1.1]
+effect.name=標準描画
+X=0.00
+Y=0.00
+拡大率=100.000
+透明度=0.00
+[2]
+layer=2
+frame=100,749
+[2.0]
+effect.name=音声ファイル
+ファイル=C:\dist\test\music.mp3
+再生位置=1.50
+[2.1]
+effect.name=音声再生
+音量=100.0
+[3]
+layer=6
+frame=700,800,899
+[3.0]
+effect.name=図形
+図形の種類=背景
+色=000000
+[3.1]
+effect.name=標準描画
+X=0.00
+Y=0.00
+拡大率=100.000
+透明度=100.00,0.00,0.00,直線移動,0
+[4]
+layer=4
+frame=100,599
+[4.0]
+effect.name=画像ファイル
+ファイル=C:\assets\lane.png
+[4.1]
+effect.name=標準描画
+X=0.00
+Y=0.00
+拡大率=100.000
+透明度=0.00
+[5]
+layer=5
+frame=100,599
+[5.0]
+effect.name=Score@SekaiObjects
+[5.1]
+effect.name=標準描画
+X=0.00
+Y=0.00
+拡大率=100.000
+透明度=0.00
+`
+
+// timelineClipWant はトラックの期待値（タイムライン上の開始・終了フレームと素材の開始フレーム）
+type timelineClipWant struct {
+	name       string
+	start, end int
+	sourceIn   int
+}
+
+// buildTestTimeline はtestTimelineObjectから汎用タイムラインを作る
+func buildTestTimeline(t *testing.T, fps int) editorTimeline {
+	t.Helper()
+	format, err := NewVideoFormat(fps, "1080p", LayoutLandscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	distDir := t.TempDir()
+	if err := imaging.Save(imaging.New(2304, 1296, image.Black), filepath.Join(distDir, "background.png")); err != nil {
+		t.Fatal(err)
+	}
+	objectFile, err := ParseObjectFile(testTimelineObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := objectPathMap{distPath: `C:\dist\test`, distDir: distDir, assetsPath: `C:\assets`, assetsDir: t.TempDir()}
+	timeline, err := buildEditorTimeline(distDir, "test", objectFile, format, paths, timelineSequences{overlay: true, preview: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return timeline
+}
+
+func TestBuildMLT(t *testing.T) {
+	for _, fps := range []int{30, 60, 120} {
+		t.Run(fmt.Sprint(fps), func(t *testing.T) {
+			data, err := buildMLT(buildTestTimeline(t, fps))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc mltDocument
+			if err := xml.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("MLT XMLを解析できません: %v", err)
+			}
+			if doc.Profile.FrameRateNum != fps || doc.Profile.FrameRateDen != 1 || doc.Tractor.Out != 899 {
+				t.Errorf("profile = %+v, tractor out = %d", doc.Profile, doc.Tractor.Out)
+			}
+
+			// トラックは.objectのレイヤー順（下から）に並ぶ
+			want := []timelineClipWant{
+				{"background", 0, 899, 0},
+				{"music", 100, 749, fps * 3 / 2},
+				{"endscreen", 600, 899, 0},
+				{"preview", 0, 899, 0},
+				{"overlay", 0, 899, 0},
+				{"solid", 700, 899, 0},
+			}
+			if len(doc.Tractor.Tracks) != len(want) || len(doc.Playlists) != len(want) {
+				t.Fatalf("トラックの数 = %d, want %d", len(doc.Tractor.Tracks), len(want))
+			}
+			producers := map[string]mltProducer{}
+			for _, producer := range doc.Producers {
+				producers[producer.ID] = producer
+			}
+			for i, w := range want {
+				track := doc.Tractor.Tracks[i]
+				if track.Producer != doc.Playlists[i].ID {
+					t.Fatalf("トラック%d: playlist = %s", i, track.Producer)
+				}
+				items := doc.Playlists[i].Items
+				entry := items[len(items)-1]
+				blank := 0
+				if len(items) == 2 {
+					blank = *items[0].Length
+				}
+				name := ""
+				for _, prop := range producers[entry.Producer].Properties {
+					if prop.Name == "kdenlive:clipname" {
+						name = prop.Value
+					}
+				}
+				if name != w.name {
+					t.Errorf("トラック%d: %s, want %s", i, name, w.name)
+				}
+				if blank != w.start || *entry.In != w.sourceIn || *entry.Out != w.sourceIn+w.end-w.start {
+					t.Errorf("%s: blank = %d, in = %d, out = %d, want %d, %d, %d", w.name, blank, *entry.In, *entry.Out, w.start, w.sourceIn, w.sourceIn+w.end-w.start)
+				}
+			}
+		})
+	}
+}
+
+// parseRational はFCPXMLの時間（"1001/30000s" や "0s"）を解析する
+func parseRational(t *testing.T, value string) *big.Rat {
+	t.Helper()
+	if !strings.HasSuffix(value, "s") {
+		t.Fatalf("時間の形式が不正です: %q", value)
+	}
+	r, ok := new(big.Rat).SetString(strings.TrimSuffix(value, "s"))
+	if !ok {
+		t.Fatalf("時間の形式が不正です: %q", value)
+	}
+	return r
+}
+
+func TestBuildFCPXML(t *testing.T) {
+	for _, fps := range []int{30, 60, 120} {
+		t.Run(fmt.Sprint(fps), func(t *testing.T) {
+			data, err := buildFCPXML(buildTestTimeline(t, fps))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc fcpxmlDocument
+			if err := xml.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("FCPXMLを解析できません: %v", err)
+			}
+
+			// frames/fps秒ちょうどで、フレームの長さの整数倍になっている
+			frameDuration := parseRational(t, doc.Resources.Formats[0].FrameDuration)
+			if frameDuration.Cmp(big.NewRat(1, int64(fps))) != 0 {
+				t.Fatalf("frameDuration = %s", frameDuration)
+			}
+			seconds := func(frames int) *big.Rat { return big.NewRat(int64(frames), int64(fps)) }
+			check := func(label, value string, frames int) {
+				t.Helper()
+				got := parseRational(t, value)
+				if got.Cmp(seconds(frames)) != 0 {
+					t.Errorf("%s = %s, want %s", label, value, seconds(frames).RatString())
+				}
+				if !new(big.Rat).Quo(got, frameDuration).IsInt() {
+					t.Errorf("%s = %s がフレームの境界にありません", label, value)
+				}
+			}
+			sequence := doc.Library.Event.Project.Sequence
+			check("sequence duration", sequence.Duration, 900)
+
+			// 連番画像は含まれず、暗転は下のクリップの不透明度になる
+			want := []struct {
+				timelineClipWant
+				lane int
+			}{
+				{timelineClipWant{"background", 0, 899, 0}, 1},
+				{timelineClipWant{"music", 100, 749, fps * 3 / 2}, -1},
+				{timelineClipWant{"endscreen", 600, 899, 0}, 2},
+			}
+			clips := sequence.Spine.Gap.Clips
+			if len(clips) != len(want) {
+				t.Fatalf("クリップの数 = %d, want %d", len(clips), len(want))
+			}
+			for i, w := range want {
+				clip := clips[i]
+				if clip.Name != w.name || clip.Lane != w.lane {
+					t.Errorf("クリップ%d: %s (lane %d), want %s (lane %d)", i, clip.Name, clip.Lane, w.name, w.lane)
+				}
+				check(w.name+" offset", clip.Offset, w.start)
+				check(w.name+" start", clip.Start, w.sourceIn)
+				check(w.name+" duration", clip.Duration, w.end-w.start+1)
+				if w.name == "endscreen" && (clip.Blend == nil || len(clip.Blend.Params) == 0) {
+					t.Errorf("エンド画面に暗転の不透明度がありません")
+				}
+			}
+			for _, asset := range doc.Resources.Assets {
+				if strings.Contains(asset.Src, "%06d") || strings.HasSuffix(asset.Src, "000000.png") {
+					t.Errorf("連番画像が含まれています: %s", asset.Src)
+				}
+			}
+		})
+	}
+}
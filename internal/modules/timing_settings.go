@@ -0,0 +1,89 @@
+package modules
+
+import (
+	"fmt"
+	"math"
+)
+
+// 開始画面のアニメーションの長さ（60fps基準のフレーム数）
+// 開始画面のオブジェクトはこの長さに合わせて作られているため変更できない
+const startScreenBaseFrames = 166
+
+// タイミング設定のデフォルト値（秒）
+const (
+	DefaultLeadIn         = 150.0 / BaseFPS
+	DefaultEndScreenDelay = 1.0
+	DefaultFadeDelay      = 161.0 / BaseFPS
+	DefaultFadeDuration   = 142.0 / BaseFPS
+	DefaultFadeHold       = 124.0 / BaseFPS
+)
+
+// 譜面・音源から自動調整する際の範囲（秒）
+const (
+	minLeadIn         = 1.0
+	maxIdleBeforeNote = 5.0
+	minEndScreenDelay = 0.5
+	// 先頭の無音区間がこれより長い場合はBGMの再生位置をずらして飛ばす
+	minSkippedSilence = 1.0
+)
+
+// AutoTiming はタイミング設定を譜面と音源の情報から自動で決定することを表す
+// 0秒も指定できるように、負の値を自動の意味で使う
+const AutoTiming = -1.0
+
+// TimingSettings は開始・終了演出のタイミング設定（秒）
+// 負の値（AutoTiming）の項目は譜面と音源の情報から自動で決定する
+type TimingSettings struct {
+	// LeadIn はUI表示からBGM開始までの長さ（InitSettingsのオフセット）
+	LeadIn float64
+	// EndScreenDelay は最後のノーツ（切り抜き終了位置）からエンド画面までの長さ
+	EndScreenDelay float64
+	// FadeDelay はエンド画面の開始からフェードアウト開始までの長さ
+	FadeDelay float64
+	// FadeDuration はフェードアウトの長さ
+	FadeDuration float64
+	// FadeHold はフェードアウト後に画面を残す長さ
+	FadeHold float64
+}
+
+// ResolveTimingSettings は未指定の項目を譜面と音源の情報から補完したタイミング設定を返す
+// musicEndは末尾の無音を除いたBGMの終了位置で、0の場合は音源の長さが不明として扱う
+func ResolveTimingSettings(overrides TimingSettings, timing ChartTiming, clipStart, clipEnd float64, clipEndSet bool, musicEnd float64) TimingSettings {
+	settings := overrides
+
+	// 最初のノーツが遅い曲では、BGMのイントロがUI表示後の待ち時間になるためリードインを短くする
+	if settings.LeadIn < 0 {
+		firstNote := math.Max(timing.FirstNoteTime-clipStart, 0)
+		settings.LeadIn = clampFloat(maxIdleBeforeNote-firstNote, minLeadIn, DefaultLeadIn)
+	}
+
+	// 最後のノーツ以降のBGMが鳴り終わるまで待ち、アウトロが切れたり無音が続いたりしないようにする
+	if settings.EndScreenDelay < 0 {
+		settings.EndScreenDelay = DefaultEndScreenDelay
+		if musicEnd > 0 && !clipEndSet {
+			settings.EndScreenDelay = math.Max(musicEnd-clipEnd, minEndScreenDelay)
+		}
+	}
+
+	if settings.FadeDelay < 0 {
+		settings.FadeDelay = DefaultFadeDelay
+	}
+	if settings.FadeDuration < 0 {
+		settings.FadeDuration = DefaultFadeDuration
+	}
+	if settings.FadeHold < 0 {
+		settings.FadeHold = DefaultFadeHold
+	}
+	return settings
+}
+
+// String はタイミング設定を表示用の文字列に変換する
+func (s TimingSettings) String() string {
+	return fmt.Sprintf("リードイン %.2f秒, エンド画面まで %.2f秒, フェード %.2f秒後に%.2f秒 (%.2f秒保持)",
+		s.LeadIn, s.EndScreenDelay, s.FadeDelay, s.FadeDuration, s.FadeHold)
+}
+
+// clampFloat は値を範囲内に収める
+func clampFloat(value, min, max float64) float64 {
+	return math.Min(math.Max(value, min), max)
+}
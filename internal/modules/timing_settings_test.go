@@ -0,0 +1,46 @@
+package modules
+
+import "testing"
+
+func TestResolveTimingSettings(t *testing.T) {
+	auto := TimingSettings{LeadIn: AutoTiming, EndScreenDelay: AutoTiming, FadeDelay: AutoTiming, FadeDuration: AutoTiming, FadeHold: AutoTiming}
+	timing := ChartTiming{FirstNoteTime: 2, LastNoteTime: 100}
+
+	t.Run("自動", func(t *testing.T) {
+		got := ResolveTimingSettings(auto, timing, 0, 100, false, 0)
+		want := TimingSettings{LeadIn: DefaultLeadIn, EndScreenDelay: DefaultEndScreenDelay, FadeDelay: DefaultFadeDelay, FadeDuration: DefaultFadeDuration, FadeHold: DefaultFadeHold}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("0秒を指定", func(t *testing.T) {
+		zero := TimingSettings{}
+		got := ResolveTimingSettings(zero, timing, 0, 100, false, 110)
+		if got != zero {
+			t.Errorf("0秒の指定が自動で上書きされました: %+v", got)
+		}
+	})
+
+	t.Run("最初のノーツが遅い曲はリードインを短くする", func(t *testing.T) {
+		late := ChartTiming{FirstNoteTime: 10, LastNoteTime: 100}
+		got := ResolveTimingSettings(auto, late, 0, 100, false, 0)
+		if got.LeadIn != minLeadIn {
+			t.Errorf("LeadIn = %v, want %v", got.LeadIn, minLeadIn)
+		}
+	})
+
+	t.Run("長いアウトロは鳴り終わるまで待つ", func(t *testing.T) {
+		got := ResolveTimingSettings(auto, timing, 0, 100, false, 112.5)
+		if got.EndScreenDelay != 12.5 {
+			t.Errorf("EndScreenDelay = %v, want 12.5", got.EndScreenDelay)
+		}
+	})
+
+	t.Run("最後のノーツで曲が終わる場合は最短の長さにする", func(t *testing.T) {
+		got := ResolveTimingSettings(auto, timing, 0, 100, false, 100.1)
+		if got.EndScreenDelay != minEndScreenDelay {
+			t.Errorf("EndScreenDelay = %v, want %v", got.EndScreenDelay, minEndScreenDelay)
+		}
+	})
+}
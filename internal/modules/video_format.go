@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 基準となるフレームレートと解像度（テンプレートの座標・フレーム数はこの値で記述する）
+const (
+	BaseFPS    = 60
+	BaseWidth  = 1920
+	BaseHeight = 1080
+)
+
+// SupportedFPS は生成に対応しているフレームレート
+var SupportedFPS = []int{30, 60, 120}
+
+// ResolutionPresets は生成に対応している解像度
+var ResolutionPresets = map[string][2]int{
+	"720p":  {1280, 720},
+	"1080p": {1920, 1080},
+	"1440p": {2560, 1440},
+	"4k":    {3840, 2160},
+}
+
+// ResolutionNames は解像度プリセットの表示順
+var ResolutionNames = []string{"720p", "1080p", "1440p", "4k"}
+
+// レイアウト（画面の向き）
+const (
+	LayoutLandscape = "landscape"
+	LayoutPortrait  = "portrait"
+)
+
+// VideoFormat は生成対象の動画のフレームレートと解像度を表す構造体
+type VideoFormat struct {
+	FPS    int
+	Width  int
+	Height int
+	Layout string
+}
+
+// NewVideoFormat はフレームレート、解像度名、レイアウトからVideoFormatを作成する
+// portraitの場合は解像度の縦横を入れ替える（1080pなら1080x1920）
+func NewVideoFormat(fps int, resolution, layout string) (VideoFormat, error) {
+	if fps == 0 {
+		fps = BaseFPS
+	}
+	if resolution == "" {
+		resolution = "1080p"
+	}
+	if layout == "" {
+		layout = LayoutLandscape
+	}
+	if layout != LayoutLandscape && layout != LayoutPortrait {
+		return VideoFormat{}, fmt.Errorf("レイアウト '%s' はサポートされていません (landscape/portrait)", layout)
+	}
+
+	supported := false
+	for _, f := range SupportedFPS {
+		if f == fps {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return VideoFormat{}, fmt.Errorf("フレームレート %d はサポートされていません (30/60/120)", fps)
+	}
+
+	size, exists := ResolutionPresets[strings.ToLower(resolution)]
+	if !exists {
+		return VideoFormat{}, fmt.Errorf("解像度 '%s' はサポートされていません (%s)", resolution, strings.Join(ResolutionNames, "/"))
+	}
+
+	if layout == LayoutPortrait {
+		return VideoFormat{FPS: fps, Width: size[1], Height: size[0], Layout: layout}, nil
+	}
+	return VideoFormat{FPS: fps, Width: size[0], Height: size[1], Layout: layout}, nil
+}
+
+// IsPortrait は縦向きレイアウトかどうかを返す
+func (f VideoFormat) IsPortrait() bool {
+	return f.Layout == LayoutPortrait
+}
+
+// Scale は基準解像度(1080p)に対する倍率を返す（短辺を基準にする）
+func (f VideoFormat) Scale() float64 {
+	if f.IsPortrait() {
+		return float64(f.Width) / BaseHeight
+	}
+	return float64(f.Height) / BaseHeight
+}
+
+// Frame は秒数をフレーム数に変換する
+func (f VideoFormat) Frame(seconds float64) int {
+	return int(math.Round(seconds * float64(f.FPS)))
+}
+
+// Frames は基準フレームレート(60fps)でのフレーム数を対象フレームレートに変換する
+func (f VideoFormat) Frames(baseFrames int) int {
+	return int(math.Round(float64(baseFrames) * float64(f.FPS) / BaseFPS))
+}
+
+// FrameEnd は基準フレームレートでの終了フレーム（終端を含む）を対象フレームレートに変換する
+// 次のオブジェクトの開始フレームと重ならないように、次フレームの直前を返す
+func (f VideoFormat) FrameEnd(baseFrame int) int {
+	return f.Frames(baseFrame+1) - 1
+}
+
+// Pos は基準解像度での座標・サイズを対象解像度に変換する
+func (f VideoFormat) Pos(value float64) float64 {
+	return value * f.Scale()
+}
+
+// String はVideoFormatを表示用の文字列に変換する
+func (f VideoFormat) String() string {
+	return fmt.Sprintf("%dx%d / %dfps", f.Width, f.Height, f.FPS)
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package utils
+
+// IsAdmin はWindows以外では常にfalseを返す
+func IsAdmin() bool {
+	return false
+}
+
+// RunAsAdmin はWindows以外では何もしない
+func RunAsAdmin() error {
+	return nil
+}
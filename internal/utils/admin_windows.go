@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// IsAdmin は現在のプロセスが管理者権限で実行されているかを確認する（Windows専用）
+func IsAdmin() bool {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	isAdmin := kernel32.NewProc("IsUserAnAdmin")
+	ret, _, _ := isAdmin.Call()
+	return ret != 0
+}
+
+// RunAsAdmin はアプリケーションを管理者権限で再起動する（Windows専用）
+func RunAsAdmin() error {
+	verb := "runas"
+	exe, _ := os.Executable()
+	cwd, _ := os.Getwd()
+	args := strings.Join(os.Args[1:], " ")
+
+	// ShellExecuteWを使用
+	shell32 := syscall.NewLazyDLL("shell32.dll")
+	shellExecute := shell32.NewProc("ShellExecuteW")
+
+	verbPtr, _ := syscall.UTF16PtrFromString(verb)
+	exePtr, _ := syscall.UTF16PtrFromString(exe)
+	argsPtr, _ := syscall.UTF16PtrFromString(args)
+	cwdPtr, _ := syscall.UTF16PtrFromString(cwd)
+
+	ret, _, _ := shellExecute.Call(
+		0,
+		uintptr(unsafe.Pointer(verbPtr)),
+		uintptr(unsafe.Pointer(exePtr)),
+		uintptr(unsafe.Pointer(argsPtr)),
+		uintptr(unsafe.Pointer(cwdPtr)),
+		1, // SW_SHOWNORMAL
+	)
+
+	if ret <= 32 {
+		return fmt.Errorf("管理者権限での再起動に失敗しました")
+	}
+
+	return nil
+}
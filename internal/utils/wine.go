@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// WinePrefix はAviUtl2を動かすWineプレフィックスのパスを返す
+// 環境変数WINEPREFIXを優先し、未設定の場合はWineの標準の~/.wineを使う
+// Windowsの場合や、drive_cのあるプレフィックスが見つからない場合は空を返す
+func WinePrefix() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	prefix := os.Getenv("WINEPREFIX")
+	if prefix == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		prefix = filepath.Join(homeDir, ".wine")
+	}
+	if info, err := os.Stat(filepath.Join(prefix, "drive_c")); err != nil || !info.IsDir() {
+		return ""
+	}
+	return prefix
+}
+
+// WindowsPath はAviUtl2に渡すパスをWindows形式（\区切り）に変換する
+// Wineプレフィックスがある場合は、WineがZ:ドライブに割り当てるルートからの絶対パスにする
+// 変換したパスはAviUtl2用なので、ツール自身がファイルを読む場合は元のパスを使うこと
+func WindowsPath(path string) string {
+	if runtime.GOOS == "windows" || WinePrefix() == "" {
+		return strings.ReplaceAll(filepath.ToSlash(path), "/", "\\")
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "Z:" + strings.ReplaceAll(filepath.ToSlash(path), "/", "\\")
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWindowsPathWine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Wineのパスの変換はWindows以外でのみ行う")
+	}
+	prefix := t.TempDir()
+	t.Setenv("WINEPREFIX", prefix)
+
+	// drive_cがない場合はWineプレフィックスとして扱わない
+	if got := WinePrefix(); got != "" {
+		t.Errorf("WinePrefix() = %q, want empty", got)
+	}
+	if got, want := WindowsPath("/home/user/dist"), `\home\user\dist`; got != want {
+		t.Errorf("WindowsPath() = %q, want %q", got, want)
+	}
+
+	if err := os.Mkdir(filepath.Join(prefix, "drive_c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := WinePrefix(); got != prefix {
+		t.Errorf("WinePrefix() = %q, want %q", got, prefix)
+	}
+	if got, want := WindowsPath("/home/user/dist"), `Z:\home\user\dist`; got != want {
+		t.Errorf("WindowsPath() = %q, want %q", got, want)
+	}
+}